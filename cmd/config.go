@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFiles son los archivos de configuración que se buscan en el
+// directorio actual si no se indica --config
+var configFiles = []string{"migrator.yaml", "migrator.yml", "migrator.toml"}
+
+// config es la configuración de conexión del CLI. Los campos vacíos no
+// pisan a los de menor precedencia.
+type config struct {
+	Driver       string `yaml:"driver" toml:"driver"`
+	DSN          string `yaml:"dsn" toml:"dsn"`
+	Dir          string `yaml:"dir" toml:"dir"`
+	Seeds        string `yaml:"seeds" toml:"seeds"`
+	Table        string `yaml:"table" toml:"table"`
+	Schema       string `yaml:"schema" toml:"schema"`
+	LockStrategy string `yaml:"lock_strategy" toml:"lock_strategy"`
+	LockKey      string `yaml:"lock_key" toml:"lock_key"`
+	LockTimeout  string `yaml:"lock_timeout" toml:"lock_timeout"`
+	// NonProduction marca la base como descartable: reset y fresh se niegan
+	// a correr sin él. Solo vale para la DSN de la misma fuente y un false
+	// explícito nunca se pisa (ver nonProductionFor).
+	NonProduction *bool `yaml:"non_production" toml:"non_production"`
+	// Env es el entorno elegido; también decide qué seeds se ejecutan
+	Env string `yaml:"-" toml:"-"`
+}
+
+// configFile es el contenido de migrator.yaml o migrator.toml. Los campos de
+// primer nivel valen para todos los entornos; cada entorno los puede pisar.
+type configFile struct {
+	config       `yaml:",inline"`
+	DefaultEnv   string            `yaml:"default_env" toml:"default_env"`
+	Environments map[string]config `yaml:"environments" toml:"environments"`
+}
+
+// merge completa c con los campos no vacíos de other
+func (c *config) merge(other config) {
+	set := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	set(&c.Driver, other.Driver)
+	set(&c.DSN, other.DSN)
+	set(&c.Dir, other.Dir)
+	set(&c.Seeds, other.Seeds)
+	set(&c.Table, other.Table)
+	set(&c.Schema, other.Schema)
+	set(&c.LockStrategy, other.LockStrategy)
+	set(&c.LockKey, other.LockKey)
+	set(&c.LockTimeout, other.LockTimeout)
+	if other.NonProduction != nil && (c.NonProduction == nil || *c.NonProduction) {
+		c.NonProduction = other.NonProduction
+	}
+}
+
+// nonProduction indica si la base está marcada como descartable
+func (c *config) nonProduction() bool {
+	return c.NonProduction != nil && *c.NonProduction
+}
+
+// fields devuelve punteros a todos los campos, para recorrerlos
+func (c *config) fields() []*string {
+	return []*string{&c.Driver, &c.DSN, &c.Dir, &c.Seeds, &c.Table, &c.Schema, &c.LockStrategy, &c.LockKey, &c.LockTimeout}
+}
+
+// envConfig lee la configuración de las variables de entorno
+func envConfig() (config, error) {
+	cfg := config{
+		Driver:       os.Getenv("DB_DRIVER"),
+		DSN:          os.Getenv("DB_URL"),
+		Dir:          os.Getenv("MIGRATIONS_DIR"),
+		Seeds:        os.Getenv("SEEDS_DIR"),
+		Table:        os.Getenv("DB_TABLE"),
+		Schema:       os.Getenv("DB_SCHEMA"),
+		LockStrategy: os.Getenv("DB_LOCK_STRATEGY"),
+		LockKey:      os.Getenv("DB_LOCK_KEY"),
+		LockTimeout:  os.Getenv("DB_LOCK_TIMEOUT"),
+	}
+	if value := os.Getenv("DB_NON_PRODUCTION"); value != "" {
+		nonProduction, err := strconv.ParseBool(value)
+		if err != nil {
+			return config{}, fmt.Errorf("DB_NON_PRODUCTION inválido: %s", value)
+		}
+		cfg.NonProduction = &nonProduction
+	}
+	return cfg, nil
+}
+
+// loadConfig arma la configuración final con precedencia flags > variables de
+// entorno > archivo. path vacío busca configFiles en el directorio actual;
+// env vacío usa MIGRATOR_ENV o el default_env del archivo.
+func loadConfig(path, env string, flags config) (config, error) {
+	file, err := readConfigFile(path)
+	if err != nil {
+		return config{}, err
+	}
+
+	if env == "" {
+		env = os.Getenv("MIGRATOR_ENV")
+	}
+	cfg, err := file.resolve(env)
+	if err != nil {
+		return config{}, err
+	}
+
+	fromEnv, err := envConfig()
+	if err != nil {
+		return config{}, err
+	}
+	fromFile := cfg
+	cfg.merge(fromEnv)
+	cfg.merge(flags)
+	cfg.NonProduction = nonProductionFor(fromFile, fromEnv, flags)
+
+	if cfg.Dir == "" {
+		cfg.Dir = "./migrations"
+	}
+	if cfg.Seeds == "" {
+		cfg.Seeds = "./seeds"
+	}
+	return cfg, nil
+}
+
+// nonProductionFor decide si la base elegida está marcada como descartable.
+// sources va de menor a mayor precedencia: las capas del archivo (primer
+// nivel y entorno) o el archivo resuelto, las variables de entorno y los
+// flags. La marca tiene que venir de la misma fuente que la DSN final: un
+// entorno marcado no vale si --dsn o DB_URL apuntan a otra base. Un false
+// explícito en cualquier fuente gana.
+func nonProductionFor(sources ...config) *bool {
+	var owner *bool
+	for _, src := range sources {
+		if src.NonProduction != nil && !*src.NonProduction {
+			return src.NonProduction
+		}
+		if src.DSN != "" {
+			owner = src.NonProduction
+		}
+	}
+	return owner
+}
+
+// readConfigFile lee el archivo de configuración. Sin path, que no exista
+// ninguno de configFiles no es un error.
+func readConfigFile(path string) (*configFile, error) {
+	if path == "" {
+		for _, name := range configFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return &configFile{}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo configuración: %w", err)
+	}
+
+	file := &configFile{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, file)
+	case ".toml":
+		_, err = toml.Decode(string(data), file)
+	default:
+		return nil, fmt.Errorf("formato de configuración desconocido: %s (yaml o toml)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error en %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// resolve combina los valores comunes con los del entorno elegido y expande
+// las referencias ${VAR}. Sin entornos definidos cualquier nombre es válido:
+// solo elige los seeds.
+func (f *configFile) resolve(env string) (config, error) {
+	if env == "" {
+		env = f.DefaultEnv
+	}
+
+	cfg := f.config
+	cfg.Env = env
+	if env != "" && len(f.Environments) > 0 {
+		envCfg, ok := f.Environments[env]
+		if !ok {
+			return config{}, fmt.Errorf("entorno desconocido: %s (disponibles: %s)", env, strings.Join(f.envNames(), ", "))
+		}
+		cfg.merge(envCfg)
+		// Un non_production: true de primer nivel no vale para un entorno que
+		// cambia la dsn
+		cfg.NonProduction = nonProductionFor(f.config, envCfg)
+	}
+
+	for _, field := range cfg.fields() {
+		expanded, err := expandEnv(*field)
+		if err != nil {
+			return config{}, err
+		}
+		*field = expanded
+	}
+	return cfg, nil
+}
+
+func (f *configFile) envNames() []string {
+	names := make([]string, 0, len(f.Environments))
+	for name := range f.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envRef reconoce las referencias ${VAR}
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv reemplaza ${VAR} por el valor de la variable de entorno. Un $
+// suelto es literal, como en una contraseña. Una variable sin definir es un
+// error: una DSN a medio armar es peor que ninguna.
+func expandEnv(s string) (string, error) {
+	var missing []string
+	expanded := envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envRef.FindStringSubmatch(ref)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", errors.New("variable de entorno no definida: " + strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
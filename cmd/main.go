@@ -1,10 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/catriel-escobar/migrator-db/migrate"
 	"github.com/jmoiron/sqlx"
@@ -17,67 +26,284 @@ import (
 
 func main() {
 	if len(os.Args) < 2 {
-		log.Fatal("usage: migrator [up|down|new|status] [flags]")
+		log.Fatal("usage: migrator [up|down|redo|goto|force|verify|repair|new|status|lock|seed|reset|fresh] [flags]")
 	}
 
 	command := os.Args[1]
 
 	// Configurar flags según el comando
 	var dryRun bool
+	var verifyChecksums bool
+	var allowOutOfOrder bool
 	var steps int
+	var force bool
+	var format string
+	var configPath, env string
+	var flags config
 
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	// Los errores de flags se informan con out, en el formato pedido
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&format, "output", "text", "Formato de salida: text o json")
+	fs.StringVar(&configPath, "config", "", "Archivo de configuración (por defecto migrator.yaml o migrator.toml)")
+	fs.StringVar(&env, "env", "", "Entorno del archivo de configuración (dev, staging, prod, ...)")
+	fs.StringVar(&flags.Dir, "dir", "", "Directorio de migraciones (por defecto ./migrations)")
+	fs.StringVar(&flags.Seeds, "seeds", "", "Directorio de seeds (por defecto ./seeds)")
+	fs.StringVar(&flags.Driver, "driver", "", "Driver de base de datos: postgres, mysql o sqlite3")
+	fs.StringVar(&flags.DSN, "dsn", "", "Connection string de la base de datos")
+	fs.StringVar(&flags.Table, "table", "", "Tabla de control (por defecto schema_migrations)")
 	switch command {
-	case "up", "down":
-		fs := flag.NewFlagSet(command, flag.ExitOnError)
+	case "up", "down", "redo", "goto", "repair", "seed", "reset":
 		fs.BoolVar(&dryRun, "dry-run", false, "Simular la ejecución sin aplicar cambios")
-		if command == "down" {
+		if command != "repair" && command != "seed" {
+			fs.BoolVar(&verifyChecksums, "verify-checksums", false, "Fallar si alguna migración aplicada fue modificada")
+		}
+		if command == "up" || command == "goto" {
+			fs.BoolVar(&allowOutOfOrder, "allow-out-of-order", false, "Aplicar migraciones pendientes anteriores a la última aplicada")
+		}
+		if command == "down" || command == "redo" {
 			fs.IntVar(&steps, "steps", 1, "Número de migraciones a revertir")
 		}
-		fs.Parse(os.Args[2:])
+	case "lock":
+		fs.BoolVar(&force, "force", false, "Liberar el lock aunque lo tenga otro proceso")
+	}
+	args, parseErr := parseArgs(fs, os.Args[2:])
+	if parseErr != nil {
+		// El parseo se corta en el error: --output puede haber quedado sin leer
+		format = outputFlag(os.Args[2:])
 	}
 
-	driver := os.Getenv("DB_DRIVER")
-	dsn := os.Getenv("DB_URL")
-
-	db, err := sqlx.Connect(driver, dsn)
+	name := command
+	if command == "lock" && len(args) > 0 {
+		name += " " + args[0]
+	}
+	out, err := newOutput(format, name, dryRun)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if parseErr != nil {
+		if !out.json {
+			fs.SetOutput(os.Stderr)
+			if errors.Is(parseErr, flag.ErrHelp) {
+				fs.Usage()
+				os.Exit(0)
+			}
+			fmt.Fprintln(os.Stderr, parseErr)
+			fs.Usage()
+			os.Exit(2)
+		}
+		out.fail(parseErr)
+	}
+
+	cfg, err := loadConfig(configPath, env, flags)
+	if err != nil {
+		out.fail(err)
+	}
+
+	// new no necesita la base de datos
+	if command == "new" {
+		if len(args) != 1 {
+			out.fail(errors.New("usage: migrator new <nombre>"))
+		}
+		created, err := migrate.CreateMigration(cfg.Dir, args[0])
+		if err != nil {
+			out.fail(err)
+		}
+		out.created(created)
+		out.done(nil)
+		return
+	}
+
+	// reset y fresh borran datos: solo en bases marcadas como descartables
+	if (command == "reset" && !dryRun || command == "fresh") && !cfg.nonProduction() {
+		out.fail(fmt.Errorf("%s borra datos y solo corre si la base está marcada como no productiva: non_production: true en el mismo nivel del archivo que define la dsn, o DB_NON_PRODUCTION=true junto con DB_URL", command))
+	}
+
+	if cfg.Driver == "" || cfg.DSN == "" {
+		out.fail(errors.New("falta la conexión: usá --driver y --dsn, DB_DRIVER y DB_URL o un archivo migrator.yaml"))
+	}
+
+	db, err := sqlx.Connect(cfg.Driver, cfg.DSN)
+	if err != nil {
+		out.fail(err)
+	}
 	defer db.Close()
 
+	// Cancelar la migración en curso ante SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// El progreso va a stderr; stdout queda para el resultado de cada comando
+	logger := migrate.SlogLogger(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	// Tiempo máximo de espera del lock, por ejemplo "2m"
+	var lockTimeout time.Duration
+	if cfg.LockTimeout != "" {
+		lockTimeout, err = time.ParseDuration(cfg.LockTimeout)
+		if err != nil {
+			out.fail(fmt.Errorf("lock_timeout inválido: %s", cfg.LockTimeout))
+		}
+	}
+
+	m, err := migrate.New(db, migrate.Options{
+		Source:           os.DirFS(cfg.Dir),
+		Seeds:            os.DirFS(cfg.Seeds),
+		TableName:        cfg.Table,
+		Schema:           cfg.Schema,
+		Lock:             migrate.LockStrategy(cfg.LockStrategy),
+		LockKey:          cfg.LockKey,
+		LockTimeout:      lockTimeout,
+		Logger:           logger,
+		DryRun:           dryRun,
+		VerifyChecksums:  verifyChecksums,
+		AllowOutOfOrder:  allowOutOfOrder,
+		AllowDestructive: cfg.nonProduction(),
+	})
+	if err != nil {
+		out.fail(err)
+	}
+
 	switch command {
 	case "up":
-		if err := migrate.Up(db, "./migrations", dryRun); err != nil {
-			log.Fatal(err)
-		}
+		result, err := m.Up(ctx)
+		out.result(result)
+		out.done(err)
 	case "down":
 		if steps < 1 {
-			log.Fatal("steps debe ser mayor a 0")
+			out.fail(errors.New("steps debe ser mayor a 0"))
+		}
+		result, err := m.Down(ctx, steps)
+		out.result(result)
+		out.done(err)
+	case "redo":
+		if steps < 1 {
+			out.fail(errors.New("steps debe ser mayor a 0"))
+		}
+		result, err := m.Redo(ctx, steps)
+		out.result(result)
+		out.done(err)
+	case "goto":
+		if len(args) != 1 {
+			out.fail(errors.New("usage: migrator goto <version> [--dry-run]"))
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			out.fail(fmt.Errorf("versión inválida: %s", args[0]))
 		}
-		if err := migrate.DownN(db, "./migrations", steps, dryRun); err != nil {
-			log.Fatal(err)
+		result, err := m.Goto(ctx, version)
+		out.result(result)
+		out.done(err)
+	case "force":
+		if len(args) != 1 {
+			out.fail(errors.New("usage: migrator force <version>"))
 		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			out.fail(fmt.Errorf("versión inválida: %s", args[0]))
+		}
+		if err := m.Force(ctx, version); err != nil {
+			out.fail(err)
+		}
+		out.forced(version)
+		out.done(nil)
+	case "verify":
+		mismatches, err := m.Verify(ctx)
+		if err != nil {
+			out.fail(err)
+		}
+		out.verified(mismatches)
+		if len(mismatches) > 0 {
+			out.done(&migrate.ChecksumError{Mismatches: mismatches})
+		}
+		out.done(nil)
+	case "repair":
+		repaired, err := m.Repair(ctx)
+		out.repaired(repaired)
+		out.done(err)
+	case "seed":
+		result, err := m.Seed(ctx, cfg.Env)
+		out.seeded(result, cfg.Env)
+		out.done(err)
+	case "reset":
+		result, err := m.Reset(ctx)
+		out.result(result)
+		out.done(err)
+	case "fresh":
+		result, err := m.Fresh(ctx)
+		out.dropped(result)
+		out.result(result)
+		out.done(err)
 	case "status":
-		versions, err := migrate.Status(db)
+		report, err := m.StatusReport(ctx)
 		if err != nil {
-			log.Fatal(err)
-		}
-		if len(versions) == 0 {
-			fmt.Println("No hay migraciones aplicadas")
-		} else {
-			fmt.Println("Migraciones aplicadas:")
-			for _, v := range versions {
-				fmt.Printf("  - %d\n", v)
-			}
+			out.fail(err)
 		}
-	case "new":
-		if len(os.Args) < 3 {
-			log.Fatal("usage: migrator new <nombre>")
+		out.status(report)
+		out.done(nil)
+	case "lock":
+		if len(args) != 1 {
+			out.fail(errors.New("usage: migrator lock [status|release --force]"))
 		}
-		if err := migrate.NewMigration("./migrations", os.Args[2]); err != nil {
-			log.Fatal(err)
+		switch args[0] {
+		case "status":
+			info, err := m.LockStatus(ctx)
+			if err != nil {
+				out.fail(err)
+			}
+			out.lock(info, false)
+			out.done(nil)
+		case "release":
+			if !force {
+				out.fail(errors.New("liberar el lock interrumpe la migración que lo tenga; revisá `migrator lock status` y usá --force"))
+			}
+			info, err := m.ReleaseLock(ctx)
+			if err != nil {
+				out.fail(err)
+			}
+			out.lock(info, true)
+			out.done(nil)
+		default:
+			out.fail(fmt.Errorf("subcomando desconocido: lock %s", args[0]))
 		}
 	default:
-		log.Fatalf("comando desconocido: %s", command)
+		out.fail(fmt.Errorf("comando desconocido: %s", command))
+	}
+}
+
+// parseArgs permite flags antes y después de los argumentos posicionales
+// (goto 5 --dry-run) y devuelve los posicionales
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return positional, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// outputFlag busca el valor de --output en args sin depender del FlagSet,
+// para informar en JSON un error en otro flag
+func outputFlag(args []string) string {
+	format := "text"
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || name != "output" {
+			continue
+		}
+		if hasValue {
+			format = value
+		} else if i+1 < len(args) {
+			format = args[i+1]
+		}
 	}
+	return format
 }
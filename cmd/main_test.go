@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestOutputFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--bogus", "--output", "json"}, "json"},
+		{[]string{"--output=json", "--steps", "x"}, "json"},
+		{[]string{"-output", "json"}, "json"},
+		{[]string{"--steps", "x"}, "text"},
+		{[]string{"--", "--output", "json"}, "text"},
+	}
+	for _, tt := range tests {
+		if got := outputFlag(tt.args); got != tt.want {
+			t.Errorf("outputFlag(%v) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/catriel-escobar/migrator-db/migrate"
+)
+
+// output muestra el resultado de cada comando como texto para personas o, con
+// --output json, como un único documento JSON en stdout
+type output struct {
+	json bool
+	doc  document
+}
+
+// document es la salida de --output json. Los nombres de los campos son
+// estables: los pipelines de CI los parsean.
+type document struct {
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	DryRun  bool   `json:"dry_run"`
+	Error   string `json:"error,omitempty"`
+	// Migrations son las migraciones ejecutadas (o planificadas en dry-run)
+	// por up, down y goto
+	Migrations []stepDoc `json:"migrations,omitzero"`
+	// Checksums son las diferencias de verify o los checksums actualizados
+	// por repair
+	Checksums []checksumDoc `json:"checksums,omitzero"`
+	Status    *statusDoc    `json:"status,omitempty"`
+	Lock      *lockDoc      `json:"lock,omitempty"`
+	Created   *createdDoc   `json:"created,omitempty"`
+	// Seeds son los seeds ejecutados (o planificados en dry-run) y
+	// SkippedSeeds los omitidos por ser de otro entorno
+	Seeds        []stepDoc `json:"seeds,omitzero"`
+	SkippedSeeds []string  `json:"skipped_seeds,omitzero"`
+	Env          string    `json:"env,omitempty"`
+	// Dropped son los objetos que eliminó fresh
+	Dropped []string `json:"dropped,omitzero"`
+	// Version es la versión que dejó force
+	Version *int `json:"version,omitempty"`
+}
+
+type stepDoc struct {
+	Version    int    `json:"version"`
+	Name       string `json:"name"`
+	Direction  string `json:"direction"`
+	DurationMS int64  `json:"duration_ms"`
+	OutOfOrder bool   `json:"out_of_order"`
+	// Repeatable indica una migración repetible, con version 0
+	Repeatable bool `json:"repeatable"`
+	// SQL solo se incluye en dry-run
+	SQL string `json:"sql,omitempty"`
+}
+
+type checksumDoc struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Applied string `json:"applied_checksum"`
+	Current string `json:"current_checksum"`
+}
+
+type statusDoc struct {
+	CurrentVersion int                   `json:"current_version"`
+	Migrations     []migrationStatusDoc  `json:"migrations"`
+	Repeatables    []repeatableStatusDoc `json:"repeatables"`
+}
+
+type migrationStatusDoc struct {
+	Version    int        `json:"version"`
+	Name       string     `json:"name"`
+	State      string     `json:"state"`
+	AppliedAt  *time.Time `json:"applied_at"`
+	HasDown    bool       `json:"has_down"`
+	OutOfOrder bool       `json:"out_of_order"`
+}
+
+type repeatableStatusDoc struct {
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+	Pending   bool       `json:"pending"`
+}
+
+type lockDoc struct {
+	Key        string     `json:"key"`
+	Held       bool       `json:"held"`
+	PID        int        `json:"pid,omitempty"`
+	Host       string     `json:"host,omitempty"`
+	AcquiredAt *time.Time `json:"acquired_at,omitempty"`
+	// Released indica que lock release liberó el lock
+	Released bool `json:"released"`
+}
+
+type createdDoc struct {
+	Version  int64  `json:"version"`
+	Name     string `json:"name"`
+	UpPath   string `json:"up_path"`
+	DownPath string `json:"down_path"`
+}
+
+func newOutput(format, command string, dryRun bool) (*output, error) {
+	switch format {
+	case "text":
+		return &output{}, nil
+	case "json":
+		return &output{json: true, doc: document{Command: command, DryRun: dryRun}}, nil
+	default:
+		return nil, fmt.Errorf("formato de salida desconocido: %s (text o json)", format)
+	}
+}
+
+// done termina el comando: si hubo un error lo informa y sale con código 1
+func (o *output) done(err error) {
+	if err != nil {
+		o.fail(err)
+	}
+	if o.json {
+		o.doc.OK = true
+		o.emit()
+	}
+}
+
+// fail informa el error y sale con código 1. En JSON se emite el documento con
+// lo que se llegó a hacer.
+func (o *output) fail(err error) {
+	if !o.json {
+		log.Fatal(err)
+	}
+	o.doc.OK = false
+	o.doc.Error = err.Error()
+	o.emit()
+	os.Exit(1)
+}
+
+func (o *output) emit() {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(o.doc); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// result muestra las migraciones ejecutadas, o el plan en dry-run
+func (o *output) result(result *migrate.Result) {
+	if result == nil {
+		return
+	}
+
+	if o.json {
+		o.doc.DryRun = result.DryRun
+		o.doc.Migrations = []stepDoc{}
+		for _, step := range result.Steps {
+			doc := stepDoc{
+				Version:    step.Version,
+				Name:       step.Name,
+				Direction:  string(step.Direction),
+				DurationMS: step.Duration.Milliseconds(),
+				OutOfOrder: step.OutOfOrder,
+				Repeatable: step.Repeatable,
+			}
+			if result.DryRun {
+				doc.SQL = step.SQL
+			}
+			o.doc.Migrations = append(o.doc.Migrations, doc)
+		}
+		return
+	}
+
+	if result.DryRun {
+		fmt.Println("\n=== MODO DRY-RUN ACTIVADO ===")
+		fmt.Println("No se realizarán cambios en la base de datos")
+		for _, step := range result.Steps {
+			if step.Repeatable && step.Direction == migrate.DirectionDown {
+				fmt.Printf("[DRY-RUN] Se limpiaría migración repetible: %s\n", step.Name)
+			} else if step.Repeatable {
+				fmt.Printf("[DRY-RUN] Se aplicaría migración repetible: %s\n", step.Name)
+			} else if step.Direction == migrate.DirectionUp {
+				fmt.Printf("[DRY-RUN] Se aplicaría migración %d: %s\n", step.Version, step.Name)
+			} else {
+				fmt.Printf("[DRY-RUN] Se revertiría migración %d: %s\n", step.Version, step.Name)
+			}
+			fmt.Println("\nContenido SQL:")
+			fmt.Println("---")
+			fmt.Println(step.SQL)
+			fmt.Println("---")
+		}
+		if len(result.Steps) == 0 {
+			fmt.Println("[DRY-RUN] No hay migraciones para ejecutar")
+		} else {
+			fmt.Printf("\n[DRY-RUN] Total: %d migración(es)\n", len(result.Steps))
+			fmt.Println("[DRY-RUN] Ningún cambio fue aplicado a la base de datos")
+		}
+		return
+	}
+
+	for _, step := range result.Steps {
+		if step.Repeatable && step.Direction == migrate.DirectionDown {
+			fmt.Printf("✓ Migración repetible limpiada: %s (%s)\n", step.Name, step.Duration.Round(time.Millisecond))
+		} else if step.Repeatable {
+			fmt.Printf("✓ Migración repetible aplicada: %s (%s)\n", step.Name, step.Duration.Round(time.Millisecond))
+		} else if step.OutOfOrder {
+			fmt.Printf("✓ Migración %d aplicada fuera de orden: %s (%s)\n", step.Version, step.Name, step.Duration.Round(time.Millisecond))
+		} else if step.Direction == migrate.DirectionUp {
+			fmt.Printf("✓ Migración %d aplicada: %s (%s)\n", step.Version, step.Name, step.Duration.Round(time.Millisecond))
+		} else {
+			fmt.Printf("✓ Migración %d revertida: %s (%s)\n", step.Version, step.Name, step.Duration.Round(time.Millisecond))
+		}
+	}
+}
+
+// seeded muestra los seeds ejecutados y los omitidos por entorno
+func (o *output) seeded(result *migrate.Result, env string) {
+	if o.json {
+		o.doc.Env = env
+		o.doc.Seeds = []stepDoc{}
+		for _, step := range result.Steps {
+			doc := stepDoc{Name: step.Name, Direction: string(step.Direction), DurationMS: step.Duration.Milliseconds()}
+			if result.DryRun {
+				doc.SQL = step.SQL
+			}
+			o.doc.Seeds = append(o.doc.Seeds, doc)
+		}
+		o.doc.SkippedSeeds = append([]string{}, result.Skipped...)
+		return
+	}
+
+	for _, step := range result.Steps {
+		if result.DryRun {
+			fmt.Printf("[DRY-RUN] Se ejecutaría el seed: %s\n", step.Name)
+			fmt.Println("---")
+			fmt.Println(step.SQL)
+			fmt.Println("---")
+		} else {
+			fmt.Printf("✓ Seed ejecutado: %s (%s)\n", step.Name, step.Duration.Round(time.Millisecond))
+		}
+	}
+	for _, name := range result.Skipped {
+		if env == "" {
+			fmt.Printf("- Seed omitido, es de un entorno y no se indicó --env: %s\n", name)
+		} else {
+			fmt.Printf("- Seed omitido, no es del entorno %s: %s\n", env, name)
+		}
+	}
+	if len(result.Steps) == 0 {
+		fmt.Println("No hay seeds para ejecutar")
+	}
+}
+
+// dropped muestra los objetos que eliminó fresh
+func (o *output) dropped(result *migrate.Result) {
+	if result == nil {
+		return
+	}
+
+	if o.json {
+		o.doc.Dropped = append([]string{}, result.Dropped...)
+		return
+	}
+
+	for _, name := range result.Dropped {
+		fmt.Printf("✓ Eliminado: %s\n", name)
+	}
+}
+
+func (o *output) forced(version int) {
+	if o.json {
+		o.doc.Version = &version
+		return
+	}
+	fmt.Printf("✓ Versión forzada a %d\n", version)
+}
+
+func (o *output) verified(mismatches []migrate.ChecksumMismatch) {
+	if o.json {
+		o.doc.Checksums = checksumDocs(mismatches)
+		return
+	}
+	if len(mismatches) == 0 {
+		fmt.Println("✓ Todas las migraciones aplicadas coinciden con sus archivos")
+	}
+}
+
+func (o *output) repaired(repaired []migrate.ChecksumMismatch) {
+	if o.json {
+		o.doc.Checksums = checksumDocs(repaired)
+		return
+	}
+	if len(repaired) == 0 {
+		fmt.Println("No hay checksums para actualizar")
+	}
+	for _, r := range repaired {
+		fmt.Printf("✓ Checksum de la migración %d actualizado\n", r.Version)
+	}
+}
+
+func checksumDocs(mismatches []migrate.ChecksumMismatch) []checksumDoc {
+	docs := []checksumDoc{}
+	for _, m := range mismatches {
+		docs = append(docs, checksumDoc{Version: m.Version, Name: m.Name, Applied: m.Applied, Current: m.Current})
+	}
+	return docs
+}
+
+// stateLabels son los nombres de cada estado en la tabla de status
+var stateLabels = map[migrate.MigrationState]string{
+	migrate.StateApplied:  "aplicada",
+	migrate.StatePending:  "pendiente",
+	migrate.StateMissing:  "pendiente (fuera de orden)",
+	migrate.StateOrphaned: "huérfana",
+	migrate.StateDirty:    "dirty",
+}
+
+// status muestra el estado de cada migración como una tabla
+func (o *output) status(report *migrate.StatusReport) {
+	if o.json {
+		doc := &statusDoc{CurrentVersion: report.Current, Migrations: []migrationStatusDoc{}}
+		for _, s := range report.Migrations {
+			doc.Migrations = append(doc.Migrations, migrationStatusDoc{
+				Version:    s.Version,
+				Name:       s.Name,
+				State:      string(s.State),
+				AppliedAt:  timePtr(s.AppliedAt),
+				HasDown:    s.HasDown,
+				OutOfOrder: s.OutOfOrder,
+			})
+		}
+		doc.Repeatables = []repeatableStatusDoc{}
+		for _, r := range report.Repeatables {
+			doc.Repeatables = append(doc.Repeatables, repeatableStatusDoc{Name: r.Name, AppliedAt: timePtr(r.AppliedAt), Pending: r.Pending})
+		}
+		o.doc.Status = doc
+		return
+	}
+
+	if len(report.Migrations) == 0 && len(report.Repeatables) == 0 {
+		fmt.Println("No hay migraciones")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSIÓN\tNOMBRE\tESTADO\tAPLICADA\tDOWN")
+	for _, s := range report.Migrations {
+		name := s.Name
+		if name == "" {
+			name = "-"
+		}
+		appliedAt := "-"
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		down := "no"
+		if s.HasDown {
+			down = "sí"
+		}
+		state := stateLabels[s.State]
+		if s.OutOfOrder {
+			state += " (fuera de orden)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", s.Version, name, state, appliedAt, down)
+	}
+	w.Flush()
+
+	fmt.Printf("\nVersión actual: %d, %d pendiente(s)\n", report.Current, report.Count(migrate.StatePending)+report.Count(migrate.StateMissing))
+	if n := report.Count(migrate.StateMissing); n > 0 {
+		fmt.Printf("⚠ %d migración(es) pendiente(s) con versión menor a la actual: up falla salvo con --allow-out-of-order\n", n)
+	}
+	if n := report.Count(migrate.StateOrphaned); n > 0 {
+		fmt.Printf("⚠ %d migración(es) aplicada(s) sin archivo\n", n)
+	}
+	if n := report.Count(migrate.StateDirty); n > 0 {
+		fmt.Printf("⚠ %d migración(es) dirty: revisá la base y usá force\n", n)
+	}
+
+	if len(report.Repeatables) > 0 {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPETIBLE\tESTADO\tAPLICADA")
+		for _, r := range report.Repeatables {
+			state, appliedAt := "aplicada", "-"
+			if !r.AppliedAt.IsZero() {
+				appliedAt = r.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			switch {
+			case r.Pending && r.AppliedAt.IsZero():
+				state = "pendiente"
+			case r.Pending:
+				state = "modificada"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, state, appliedAt)
+		}
+		w.Flush()
+	}
+}
+
+// lock muestra el estado del lock y, si está tomado, quién lo tiene. Con
+// released indica el resultado de lock release.
+func (o *output) lock(info migrate.LockInfo, released bool) {
+	if o.json {
+		o.doc.Lock = &lockDoc{
+			Key:        info.Key,
+			Held:       info.Held && !released,
+			PID:        info.PID,
+			Host:       info.Host,
+			AcquiredAt: timePtr(info.AcquiredAt),
+			Released:   released && info.Held,
+		}
+		return
+	}
+
+	if released {
+		if !info.Held {
+			fmt.Println("El lock no estaba tomado")
+		} else {
+			fmt.Printf("✓ Lock liberado (PID %d)\n", info.PID)
+		}
+		return
+	}
+
+	if !info.Held {
+		fmt.Printf("Lock libre (clave %s)\n", info.Key)
+		return
+	}
+
+	fmt.Printf("Lock tomado (clave %s)\n", info.Key)
+	if info.PID != 0 {
+		fmt.Printf("  PID:   %d\n", info.PID)
+	}
+	if info.Host != "" {
+		fmt.Printf("  Host:  %s\n", info.Host)
+	}
+	if !info.AcquiredAt.IsZero() {
+		fmt.Printf("  Desde: %s (%s)\n", info.AcquiredAt.Format(time.RFC3339), time.Since(info.AcquiredAt).Round(time.Second))
+	}
+}
+
+func (o *output) created(created *migrate.CreatedMigration) {
+	if o.json {
+		o.doc.Created = &createdDoc{
+			Version:  created.Version,
+			Name:     created.Name,
+			UpPath:   created.UpPath,
+			DownPath: created.DownPath,
+		}
+		return
+	}
+	fmt.Printf("✓ Migración creada: %d_%s\n", created.Version, created.Name)
+	fmt.Printf("  UP:   %s\n", created.UpPath)
+	fmt.Printf("  DOWN: %s\n", created.DownPath)
+}
+
+// timePtr devuelve nil para el tiempo cero, así en JSON queda null
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ChecksumMismatch describe una migración aplicada cuyo archivo cambió
+type ChecksumMismatch struct {
+	Version int
+	Name    string
+	// Applied es el checksum guardado al aplicar la migración
+	Applied string
+	// Current es el checksum del archivo actual
+	Current string
+}
+
+// ChecksumError lo devuelven Up, Down y Goto con Options.VerifyChecksums
+// cuando hay migraciones aplicadas modificadas
+type ChecksumError struct {
+	Mismatches []ChecksumMismatch
+}
+
+func (e *ChecksumError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d migración(es) aplicada(s) fueron modificadas:", len(e.Mismatches))
+	for _, mm := range e.Mismatches {
+		fmt.Fprintf(&b, "\n  - %d %s: aplicada %s, actual %s", mm.Version, mm.Name, mm.Applied, mm.Current)
+	}
+	b.WriteString("\nsi el cambio es intencional ejecutá 'migrator repair'")
+	return b.String()
+}
+
+// Verify compara el checksum guardado de cada migración aplicada con el de su
+// archivo actual y devuelve las que no coinciden. Las migraciones aplicadas
+// sin checksum (antes de que existiera la columna) no se comparan.
+func (m *Migrator) Verify(ctx context.Context) ([]ChecksumMismatch, error) {
+	if err := ensure(ctx, m.db, m.table); err != nil {
+		return nil, err
+	}
+
+	migrations, err := m.load()
+	if err != nil {
+		return nil, fmt.Errorf("error cargando migraciones: %w", err)
+	}
+	return m.verify(ctx, migrations)
+}
+
+func (m *Migrator) verify(ctx context.Context, migrations []Migration) ([]ChecksumMismatch, error) {
+	stored, err := checksums(ctx, m.db, m.table)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo checksums: %w", err)
+	}
+
+	var mismatches []ChecksumMismatch
+	for _, mig := range migrations {
+		applied, ok := stored[mig.Version]
+		if !ok || applied == "" {
+			continue
+		}
+		if current := mig.Checksum(); current != applied {
+			mismatches = append(mismatches, ChecksumMismatch{
+				Version: mig.Version,
+				Name:    mig.Name,
+				Applied: applied,
+				Current: current,
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// Repair acepta el contenido actual de los archivos: guarda el checksum de
+// cada migración aplicada cuyo valor no coincide o no existe, y devuelve las
+// migraciones actualizadas. No ejecuta ningún script.
+func (m *Migrator) Repair(ctx context.Context) ([]ChecksumMismatch, error) {
+	if !m.opts.DryRun {
+		lockCtx, unlock, err := m.lock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		ctx = lockCtx
+	}
+
+	if err := ensure(ctx, m.db, m.table); err != nil {
+		return nil, err
+	}
+
+	migrations, err := m.load()
+	if err != nil {
+		return nil, fmt.Errorf("error cargando migraciones: %w", err)
+	}
+
+	stored, err := checksums(ctx, m.db, m.table)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo checksums: %w", err)
+	}
+
+	var repaired []ChecksumMismatch
+	for _, mig := range migrations {
+		applied, ok := stored[mig.Version]
+		current := mig.Checksum()
+		if !ok || applied == current {
+			continue
+		}
+
+		if !m.opts.DryRun {
+			if err := updateChecksum(ctx, m.db, m.table, mig.Version, current); err != nil {
+				return repaired, fmt.Errorf("error actualizando checksum de la migración %d: %w", mig.Version, err)
+			}
+		}
+		m.log(ctx, slog.LevelInfo, "checksum actualizado", m.migrationAttrs(mig, DirectionUp, slog.String("checksum", current))...)
+
+		repaired = append(repaired, ChecksumMismatch{
+			Version: mig.Version,
+			Name:    mig.Name,
+			Applied: applied,
+			Current: current,
+		})
+	}
+	return repaired, nil
+}
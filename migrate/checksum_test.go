@@ -0,0 +1,124 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	t.Run("ignores line endings and trailing whitespace", func(t *testing.T) {
+		a := Migration{UpSQL: "CREATE TABLE users (id INTEGER);\nDROP TABLE x;\n"}
+		b := Migration{UpSQL: "\r\nCREATE TABLE users (id INTEGER);  \r\nDROP TABLE x;\t\r\n\r\n"}
+
+		if a.Checksum() != b.Checksum() {
+			t.Error("checksums should match after normalization")
+		}
+	})
+
+	t.Run("detects content changes", func(t *testing.T) {
+		a := Migration{UpSQL: "CREATE TABLE users (id INTEGER);"}
+		b := Migration{UpSQL: "CREATE TABLE users (id BIGINT);"}
+
+		if a.Checksum() == b.Checksum() {
+			t.Error("checksums should differ")
+		}
+	})
+}
+
+func TestVerifyAndRepair(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	dir := SetupTestMigrations(t)
+	ctx := context.Background()
+
+	m, _ := New(db, Options{Source: os.DirFS(dir), VerifyChecksums: true})
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("setup Up failed: %v", err)
+	}
+
+	t.Run("no mismatches after apply", func(t *testing.T) {
+		mismatches, err := m.Verify(ctx)
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if len(mismatches) != 0 {
+			t.Errorf("unexpected mismatches: %+v", mismatches)
+		}
+	})
+
+	// Editar una migración ya aplicada
+	CreateMigrationFile(t, dir, "2_create_posts.up.sql", `CREATE TABLE posts (id INTEGER PRIMARY KEY);`)
+
+	t.Run("verify reports modified migration", func(t *testing.T) {
+		mismatches, err := m.Verify(ctx)
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if len(mismatches) != 1 || mismatches[0].Version != 2 {
+			t.Fatalf("expected mismatch for version 2, got %+v", mismatches)
+		}
+	})
+
+	t.Run("up fails with VerifyChecksums", func(t *testing.T) {
+		CreateMigrationFile(t, dir, "4_add_tags.up.sql", `CREATE TABLE tags (id INTEGER);`)
+
+		var checksumErr *ChecksumError
+		if _, err := m.Up(ctx); !errors.As(err, &checksumErr) {
+			t.Fatalf("expected *ChecksumError, got %v", err)
+		}
+		if TableExists(t, db, "tags") {
+			t.Error("pending migration was applied despite checksum mismatch")
+		}
+	})
+
+	t.Run("repair accepts new checksums", func(t *testing.T) {
+		repaired, err := m.Repair(ctx)
+		if err != nil {
+			t.Fatalf("Repair failed: %v", err)
+		}
+		if len(repaired) != 1 || repaired[0].Version != 2 {
+			t.Fatalf("expected version 2 repaired, got %+v", repaired)
+		}
+
+		if _, err := m.Up(ctx); err != nil {
+			t.Fatalf("Up after Repair failed: %v", err)
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2, 3, 4})
+	})
+}
+
+func TestVerifySkipsLegacyRows(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	m, _ := New(db, Options{Source: os.DirFS(SetupTestMigrations(t))})
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("setup Up failed: %v", err)
+	}
+	// Filas aplicadas antes de que existiera la columna checksum
+	if _, err := db.Exec(`UPDATE schema_migrations SET checksum = NULL`); err != nil {
+		t.Fatalf("error limpiando checksums: %v", err)
+	}
+
+	mismatches, err := m.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Errorf("legacy rows should not be reported: %+v", mismatches)
+	}
+
+	repaired, err := m.Repair(ctx)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if len(repaired) != 3 {
+		t.Errorf("expected 3 checksums filled, got %d", len(repaired))
+	}
+}
@@ -3,36 +3,92 @@ package migrate
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
+// CreatedMigration describe los archivos generados por CreateMigration
+type CreatedMigration struct {
+	Version  int64
+	Name     string
+	UpPath   string
+	DownPath string
+}
+
+// NewMigration crea los archivos up y down de una migración nueva en dir
 func NewMigration(dir, name string) error {
+	_, err := CreateMigration(dir, name)
+	return err
+}
+
+// CreateMigration crea los archivos up y down de una migración nueva en dir,
+// usando como versión el timestamp actual, y devuelve sus rutas
+func CreateMigration(dir, name string) (*CreatedMigration, error) {
 	// Validar que el directorio existe, sino crearlo
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("error creando directorio: %w", err)
+		return nil, fmt.Errorf("error creando directorio: %w", err)
 	}
 
 	if name == "" {
-		return errors.New("el nombre de la migración no puede estar vacío")
+		return nil, errors.New("el nombre de la migración no puede estar vacío")
 	}
 
-	version := time.Now().Unix()
-
-	up := filepath.Join(dir, fmt.Sprintf("%d_%s.up.sql", version, name))
+	version, up, err := reserveVersion(dir, name, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
 	down := filepath.Join(dir, fmt.Sprintf("%d_%s.down.sql", version, name))
 
 	if err := os.WriteFile(up, []byte("-- UP\n"), 0644); err != nil {
-		return fmt.Errorf("error creando archivo up: %w", err)
+		return nil, fmt.Errorf("error creando archivo up: %w", err)
 	}
 	if err := os.WriteFile(down, []byte("-- DOWN\n"), 0644); err != nil {
-		return fmt.Errorf("error creando archivo down: %w", err)
+		return nil, fmt.Errorf("error creando archivo down: %w", err)
+	}
+
+	return &CreatedMigration{Version: version, Name: name, UpPath: up, DownPath: down}, nil
+}
+
+// reserveVersion busca la primera versión libre a partir de version y crea
+// su archivo up de forma exclusiva, para que dos migraciones creadas en el
+// mismo segundo no compartan número.
+func reserveVersion(dir, name string, version int64) (int64, string, error) {
+	for ; ; version++ {
+		taken, err := versionExists(dir, version)
+		if err != nil {
+			return 0, "", fmt.Errorf("error leyendo directorio: %w", err)
+		}
+		if taken {
+			continue
+		}
+
+		up := filepath.Join(dir, fmt.Sprintf("%d_%s.up.sql", version, name))
+		f, err := os.OpenFile(up, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return 0, "", fmt.Errorf("error creando archivo up: %w", err)
+		}
+		f.Close()
+		return version, up, nil
 	}
+}
 
-	fmt.Printf("✓ Migración creada: %d_%s\n", version, name)
-	fmt.Printf("  UP:   %s\n", up)
-	fmt.Printf("  DOWN: %s\n", down)
+func versionExists(dir string, version int64) (bool, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
 
-	return nil
+	prefix := fmt.Sprintf("%d_", version)
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), prefix) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
@@ -347,3 +347,21 @@ func TestNewMigrationErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	created, err := CreateMigration(dir, "create_users")
+	if err != nil {
+		t.Fatalf("CreateMigration failed: %v", err)
+	}
+
+	if created.Name != "create_users" || created.Version == 0 {
+		t.Errorf("unexpected result: %+v", created)
+	}
+	for _, path := range []string{created.UpPath, created.DownPath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("file %s was not created: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DirtyError indica que una migración falló a mitad de camino y la base de
+// datos puede haber quedado con cambios parciales. Up, Down y Goto se niegan
+// a continuar hasta que un operador revise el estado y ejecute Force.
+type DirtyError struct {
+	Version int
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("la base de datos está en estado dirty: la migración %d falló a mitad de camino; "+
+		"revisá el estado a mano y ejecutá 'migrator force <version>'", e.Version)
+}
+
+// Force deja version como última migración aplicada, sin ejecutar SQL
+func Force(db *sqlx.DB, version int) error {
+	m, err := New(db, Options{})
+	if err != nil {
+		return err
+	}
+	return m.Force(context.Background(), version)
+}
+
+// checkDirty devuelve un *DirtyError si alguna migración quedó marcada
+func (m *Migrator) checkDirty(ctx context.Context) error {
+	version, dirty, err := dirtyVersion(ctx, m.db, m.table)
+	if err != nil {
+		return fmt.Errorf("error verificando estado dirty: %w", err)
+	}
+	if dirty {
+		return &DirtyError{Version: version}
+	}
+	return nil
+}
+
+// Force corrige la tabla de control a mano después de arreglar una migración
+// fallida: quita las marcas dirty, registra version como aplicada y da por no
+// aplicadas las versiones posteriores. No ejecuta ningún script. Con version 0
+// la tabla de control queda vacía.
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if version < 0 {
+		return errors.New("la versión no puede ser negativa")
+	}
+
+	if m.opts.DryRun {
+		m.log(ctx, slog.LevelInfo, "dry-run: se forzaría la versión", slog.Int("version", version), slog.Bool("dry_run", true))
+		return nil
+	}
+
+	ctx, unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := ensure(ctx, m.db, m.table); err != nil {
+		return err
+	}
+
+	if err := forceVersion(ctx, m.db, m.table, version); err != nil {
+		return fmt.Errorf("error forzando versión %d: %w", version, err)
+	}
+
+	m.log(ctx, slog.LevelInfo, "versión forzada", slog.Int("version", version), slog.Bool("dry_run", false))
+	return nil
+}
@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestDirtyState(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	dir := SetupTestMigrations(t)
+
+	if err := Goto(db, dir, 2, false); err != nil {
+		t.Fatalf("setup Goto failed: %v", err)
+	}
+
+	// Simular una migración que se cortó a mitad de camino
+	if _, err := db.Exec(`INSERT INTO schema_migrations(version, dirty) VALUES(3, TRUE)`); err != nil {
+		t.Fatalf("error marcando dirty: %v", err)
+	}
+
+	t.Run("dirty version is not reported as applied", func(t *testing.T) {
+		AssertMigrationsApplied(t, db, []int{1, 2})
+	})
+
+	t.Run("up refuses to run", func(t *testing.T) {
+		err := Up(db, dir, false)
+
+		var dirtyErr *DirtyError
+		if !errors.As(err, &dirtyErr) {
+			t.Fatalf("expected *DirtyError, got %v", err)
+		}
+		if dirtyErr.Version != 3 {
+			t.Errorf("expected dirty version 3, got %d", dirtyErr.Version)
+		}
+	})
+
+	t.Run("down refuses to run", func(t *testing.T) {
+		var dirtyErr *DirtyError
+		if err := Down(db, dir, false); !errors.As(err, &dirtyErr) {
+			t.Fatalf("expected *DirtyError, got %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1, 2})
+	})
+
+	t.Run("force marks the version as applied", func(t *testing.T) {
+		if err := Force(db, 3); err != nil {
+			t.Fatalf("Force failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1, 2, 3})
+
+		if err := Up(db, dir, false); err != nil {
+			t.Errorf("Up after Force failed: %v", err)
+		}
+	})
+
+	t.Run("force to a lower version forgets the ones above", func(t *testing.T) {
+		if err := Force(db, 1); err != nil {
+			t.Fatalf("Force failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1})
+	})
+
+	t.Run("force zero empties the state table", func(t *testing.T) {
+		if err := Force(db, 0); err != nil {
+			t.Fatalf("Force failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{})
+	})
+}
+
+func TestFailedMigrationIsNotLeftDirty(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	dir := SetupTestMigrations(t)
+	CreateInvalidMigration(t, dir)
+
+	if err := Up(db, dir, false); err == nil {
+		t.Fatal("esperaba error con SQL inválido")
+	}
+
+	// SQLite hace rollback del DDL, así que la marca dirty se quita
+	_, dirty, err := dirtyVersion(context.Background(), db, DefaultTableName)
+	if err != nil {
+		t.Fatalf("dirtyVersion failed: %v", err)
+	}
+	if dirty {
+		t.Error("la migración fallida quedó marcada como dirty")
+	}
+}
+
+func TestEnsureUpgradesOldStateTable(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	// Tabla de control creada por una versión anterior, sin la columna dirty
+	_, err := db.Exec(`CREATE TABLE schema_migrations (
+		version BIGINT PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		t.Fatalf("error creando tabla: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations(version) VALUES(1)`); err != nil {
+		t.Fatalf("error insertando versión: %v", err)
+	}
+
+	if err := Up(db, SetupTestMigrations(t), false); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	AssertMigrationsApplied(t, db, []int{1, 2, 3})
+}
+
+func TestFailedMigrationIsNotLeftDirtyWithDriverAlias(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	// "sqlite" es el nombre del driver de modernc.org/sqlite: mismo motor
+	alias := sqlx.NewDb(db.DB, "sqlite")
+
+	dir := SetupTestMigrations(t)
+	CreateInvalidMigration(t, dir)
+
+	m, err := New(alias, Options{Source: os.DirFS(dir)})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err == nil {
+		t.Fatal("expected error with invalid SQL")
+	}
+
+	_, dirty, err := dirtyVersion(context.Background(), alias, DefaultTableName)
+	if err != nil {
+		t.Fatalf("dirtyVersion failed: %v", err)
+	}
+	if dirty {
+		t.Error("the failed migration was left dirty")
+	}
+}
@@ -0,0 +1,221 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrDestructive indica que se llamó a Reset o Fresh sin
+// Options.AllowDestructive
+var ErrDestructive = errors.New("reset y fresh borran datos: habilitalos con Options.AllowDestructive solo en bases no productivas")
+
+// Reset revierte todas las migraciones aplicadas, de la más reciente a la más
+// antigua, con sus scripts down. Antes ejecuta los R_<nombre>.down.sql de las
+// migraciones repetibles aplicadas, que tienen que existir: una vista o un
+// trigger pueden impedir borrar sus tablas. También olvida las repetibles y
+// los seeds aplicados, para que el próximo Up y Seed los vuelvan a ejecutar.
+// Salvo en dry-run necesita Options.AllowDestructive.
+func (m *Migrator) Reset(ctx context.Context) (*Result, error) {
+	if !m.opts.DryRun && !m.opts.AllowDestructive {
+		return &Result{}, ErrDestructive
+	}
+	return m.locked(ctx, func(ctx context.Context, result *Result) error {
+		if _, _, err := m.prepare(ctx); err != nil {
+			return err
+		}
+		if err := m.revertRepeatables(ctx, result); err != nil {
+			return err
+		}
+		if err := m.apply(ctx, planGoto(0), false, result); err != nil {
+			return err
+		}
+		if m.opts.DryRun {
+			return nil
+		}
+		return m.forgetScripts(ctx)
+	})
+}
+
+// Fresh elimina todas las tablas, vistas, secuencias y tipos del schema de la
+// tabla de control y después aplica todas las migraciones, con un solo lock.
+// Borra todos los datos: está pensado para bases locales y de tests. Los
+// nombres eliminados quedan en Result.Dropped. No admite dry-run y necesita
+// Options.AllowDestructive.
+func (m *Migrator) Fresh(ctx context.Context) (*Result, error) {
+	if m.opts.DryRun {
+		return &Result{DryRun: true}, errors.New("fresh no admite dry-run")
+	}
+	if !m.opts.AllowDestructive {
+		return &Result{}, ErrDestructive
+	}
+	return m.locked(ctx, func(ctx context.Context, result *Result) error {
+		if err := m.dropAll(ctx, result); err != nil {
+			return err
+		}
+		return m.apply(ctx, planUp, true, result)
+	})
+}
+
+// forgetScripts vacía las tablas de control de repetibles y seeds
+func (m *Migrator) forgetScripts(ctx context.Context) error {
+	for _, table := range []string{m.repeatables, m.seeds} {
+		if err := ensureRepeatable(ctx, m.db, table); err != nil {
+			return err
+		}
+		if _, err := m.db.ExecContext(ctx, `DELETE FROM `+table); err != nil {
+			return fmt.Errorf("error limpiando %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// schemaObject es un objeto a eliminar. Kind es el tipo tal como va en DROP.
+type schemaObject struct {
+	Kind string `db:"kind"`
+	Name string `db:"name"`
+}
+
+// lockTables son las tablas de los lockers, que no se eliminan: el lock está
+// tomado mientras corre Fresh
+var lockTables = map[string]bool{DefaultLockTable: true, "migration_lock": true}
+
+// dropAll elimina los objetos del schema en una sola conexión, para que
+// desactivar las foreign keys valga para todos los DROP
+func (m *Migrator) dropAll(ctx context.Context, result *Result) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("error obteniendo conexión: %w", err)
+	}
+	defer conn.Close()
+
+	var schema string
+	var objects []schemaObject
+	switch m.dialect {
+	case DialectPostgres:
+		schema, objects, err = postgresObjects(ctx, conn, m.opts.Schema)
+	case DialectMySQL:
+		schema, objects, err = mysqlObjects(ctx, conn, m.opts.Schema)
+	case DialectSQLite:
+		schema, objects, err = sqliteObjects(ctx, conn, m.opts.Schema)
+	default:
+		return fmt.Errorf("fresh no está soportado para el driver %s", m.db.DriverName())
+	}
+	if err != nil {
+		return fmt.Errorf("error listando los objetos del schema: %w", err)
+	}
+
+	restore, err := disableForeignKeys(ctx, conn, m.dialect)
+	if err != nil {
+		return fmt.Errorf("error desactivando foreign keys: %w", err)
+	}
+	defer restore()
+
+	for _, obj := range objects {
+		if lockTables[obj.Name] {
+			continue
+		}
+
+		query := `DROP ` + obj.Kind + ` IF EXISTS ` + qualifiedName(m.dialect, schema, obj.Name)
+		if m.dialect == DialectPostgres {
+			query += ` CASCADE`
+		}
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("error eliminando %s %s: %w", obj.Kind, obj.Name, err)
+		}
+
+		m.log(ctx, slog.LevelInfo, "objeto eliminado", slog.String("kind", obj.Kind), slog.String("name", obj.Name))
+		result.Dropped = append(result.Dropped, obj.Name)
+	}
+	return nil
+}
+
+// postgresObjects lista las tablas, vistas, secuencias, tipos y dominios del
+// schema, sin los que pertenecen a una extensión
+func postgresObjects(ctx context.Context, conn *sqlx.Conn, schema string) (string, []schemaObject, error) {
+	if schema == "" {
+		if err := conn.GetContext(ctx, &schema, `SELECT current_schema()`); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var objects []schemaObject
+	err := conn.SelectContext(ctx, &objects, `
+		SELECT CASE c.relkind
+				WHEN 'v' THEN 'VIEW'
+				WHEN 'm' THEN 'MATERIALIZED VIEW'
+				WHEN 'S' THEN 'SEQUENCE'
+				WHEN 'f' THEN 'FOREIGN TABLE'
+				ELSE 'TABLE'
+			END AS kind, c.relname AS name
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f')
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')
+		UNION ALL
+		SELECT CASE t.typtype WHEN 'd' THEN 'DOMAIN' ELSE 'TYPE' END AS kind, t.typname AS name
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE n.nspname = $1
+			AND (t.typtype IN ('e', 'd', 'r') OR (t.typtype = 'c' AND EXISTS (SELECT 1 FROM pg_class c WHERE c.oid = t.typrelid AND c.relkind = 'c')))
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_type'::regclass AND d.objid = t.oid AND d.deptype = 'e')`, schema)
+	return schema, objects, err
+}
+
+// mysqlObjects lista las tablas, vistas y secuencias (MariaDB) de la base
+func mysqlObjects(ctx context.Context, conn *sqlx.Conn, schema string) (string, []schemaObject, error) {
+	var objects []schemaObject
+	err := conn.SelectContext(ctx, &objects, `
+		SELECT CASE table_type WHEN 'VIEW' THEN 'VIEW' WHEN 'SEQUENCE' THEN 'SEQUENCE' ELSE 'TABLE' END AS kind,
+			table_name AS name
+		FROM information_schema.tables
+		WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE())`, schema)
+	return schema, objects, err
+}
+
+// sqliteObjects lista las tablas y vistas de la base (o de la base adjunta
+// schema). Índices y triggers se eliminan con su tabla.
+func sqliteObjects(ctx context.Context, conn *sqlx.Conn, schema string) (string, []schemaObject, error) {
+	master := "sqlite_master"
+	if schema != "" {
+		master = quoteIdent(DialectSQLite, schema) + ".sqlite_master"
+	}
+
+	var objects []schemaObject
+	err := conn.SelectContext(ctx, &objects, `
+		SELECT CASE type WHEN 'view' THEN 'VIEW' ELSE 'TABLE' END AS kind, name
+		FROM `+master+`
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'`)
+	return schema, objects, err
+}
+
+// disableForeignKeys desactiva las foreign keys en la conexión para poder
+// eliminar las tablas en cualquier orden. PostgreSQL usa CASCADE en su lugar.
+func disableForeignKeys(ctx context.Context, conn *sqlx.Conn, dialect Dialect) (func(), error) {
+	var disable, enable string
+	switch dialect {
+	case DialectMySQL:
+		disable, enable = `SET FOREIGN_KEY_CHECKS = 0`, `SET FOREIGN_KEY_CHECKS = 1`
+	case DialectSQLite:
+		var enabled bool
+		if err := conn.GetContext(ctx, &enabled, `PRAGMA foreign_keys`); err != nil {
+			return nil, err
+		}
+		if !enabled {
+			return func() {}, nil
+		}
+		disable, enable = `PRAGMA foreign_keys = OFF`, `PRAGMA foreign_keys = ON`
+	default:
+		return func() {}, nil
+	}
+
+	if _, err := conn.ExecContext(ctx, disable); err != nil {
+		return nil, err
+	}
+	return func() {
+		conn.ExecContext(context.WithoutCancel(ctx), enable)
+	}, nil
+}
@@ -0,0 +1,181 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func freshSource() fstest.MapFS {
+	return fstest.MapFS{
+		"1_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);")},
+		"1_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"2_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id));\nCREATE INDEX idx_posts_user ON posts(user_id);")},
+		"2_posts.down.sql": {Data: []byte("DROP TABLE posts;")},
+		"R_views.sql":      {Data: []byte("DROP VIEW IF EXISTS user_posts;\nCREATE VIEW user_posts AS SELECT u.email, p.id FROM users u JOIN posts p ON p.user_id = u.id;")},
+		"R_views.down.sql": {Data: []byte("DROP VIEW IF EXISTS user_posts;")},
+	}
+}
+
+func viewExists(t *testing.T, db *sqlx.DB, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'view' AND name = ?`, name); err != nil {
+		t.Fatalf("error verificando vista: %v", err)
+	}
+	return count > 0
+}
+
+func TestReset(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	seeds := fstest.MapFS{"users.sql": {Data: []byte("INSERT INTO users (email) VALUES ('admin@example.com');")}}
+	m, _ := New(db, Options{Source: freshSource(), Seeds: seeds, AllowDestructive: true})
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if _, err := m.Seed(ctx, ""); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+
+	t.Run("dry run only returns the plan", func(t *testing.T) {
+		dry, _ := New(db, Options{Source: freshSource(), DryRun: true})
+		result, err := dry.Reset(ctx)
+		if err != nil {
+			t.Fatalf("Reset dry-run failed: %v", err)
+		}
+		if len(result.Steps) != 3 || !result.Steps[0].Repeatable || result.Steps[0].Direction != DirectionDown ||
+			result.Steps[1].Version != 2 || result.Steps[2].Version != 1 {
+			t.Errorf("unexpected plan: %+v", result.Steps)
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2})
+		if !viewExists(t, db, "user_posts") {
+			t.Error("dry-run dropped the repeatable view")
+		}
+	})
+
+	t.Run("fails before changing anything without repeatable down scripts", func(t *testing.T) {
+		source := freshSource()
+		delete(source, "R_views.down.sql")
+		noDown, _ := New(db, Options{Source: source, AllowDestructive: true})
+		_, err := noDown.Reset(ctx)
+		if err == nil || !strings.Contains(err.Error(), "R_views.down.sql") {
+			t.Fatalf("expected missing down script error, got %v", err)
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2})
+	})
+
+	t.Run("reverts everything and forgets repeatables and seeds", func(t *testing.T) {
+		result, err := m.Reset(ctx)
+		if err != nil {
+			t.Fatalf("Reset failed: %v", err)
+		}
+		if len(result.Steps) != 3 || result.Steps[0].Name != "views" {
+			t.Errorf("expected the repeatable and 2 migrations, got %+v", result.Steps)
+		}
+		AssertMigrationsApplied(t, db, []int{})
+		if viewExists(t, db, "user_posts") {
+			t.Error("the repeatable view should be dropped")
+		}
+
+		result, err = m.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+		if len(result.Steps) != 3 || !result.Steps[2].Repeatable {
+			t.Errorf("expected the repeatable to run again, got %+v", result.Steps)
+		}
+		result, err = m.Seed(ctx, "")
+		if err != nil || len(result.Steps) != 1 {
+			t.Errorf("expected the seed to run again, got %+v, %v", result, err)
+		}
+	})
+
+	t.Run("requires AllowDestructive", func(t *testing.T) {
+		m.Up(ctx)
+		guarded, _ := New(db, Options{Source: freshSource()})
+		if _, err := guarded.Reset(ctx); !errors.Is(err, ErrDestructive) {
+			t.Errorf("expected ErrDestructive, got %v", err)
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2})
+	})
+
+	t.Run("nothing applied is a no-op", func(t *testing.T) {
+		m.Reset(ctx)
+		if _, err := m.Reset(ctx); err != nil {
+			t.Errorf("Reset on an empty database failed: %v", err)
+		}
+	})
+}
+
+func TestFresh(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	db.MustExec("PRAGMA foreign_keys = ON")
+	m, _ := New(db, Options{Source: freshSource(), AllowDestructive: true})
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	// Objetos que no vienen de ninguna migración y datos que se pierden
+	db.MustExec(`CREATE TABLE "leftover table" (id INTEGER)`)
+	db.MustExec("INSERT INTO users (id, email) VALUES (1, 'a@example.com')")
+	db.MustExec("INSERT INTO posts (user_id) VALUES (1)")
+
+	if _, err := (&Migrator{opts: Options{DryRun: true, AllowDestructive: true}}).Fresh(ctx); err == nil {
+		t.Error("expected fresh to reject dry-run")
+	}
+
+	guarded, _ := New(db, Options{Source: freshSource()})
+	if _, err := guarded.Fresh(ctx); !errors.Is(err, ErrDestructive) {
+		t.Fatalf("expected ErrDestructive, got %v", err)
+	}
+	if !TableExists(t, db, "leftover table") {
+		t.Fatal("fresh without AllowDestructive dropped objects")
+	}
+
+	result, err := m.Fresh(ctx)
+	if err != nil {
+		t.Fatalf("Fresh failed: %v", err)
+	}
+
+	dropped := map[string]bool{}
+	for _, name := range result.Dropped {
+		dropped[name] = true
+	}
+	for _, name := range []string{"users", "posts", "user_posts", "leftover table", "schema_migrations", "schema_migrations_repeatable"} {
+		if !dropped[name] {
+			t.Errorf("expected %s to be dropped, got %v", name, result.Dropped)
+		}
+	}
+	if dropped["migration_lock"] {
+		t.Error("the lock table must not be dropped")
+	}
+
+	if TableExists(t, db, "leftover table") {
+		t.Error("leftover table should be gone")
+	}
+	AssertMigrationsApplied(t, db, []int{1, 2})
+	if len(result.Steps) != 3 {
+		t.Errorf("expected all migrations and the repeatable, got %+v", result.Steps)
+	}
+
+	var users int
+	db.Get(&users, "SELECT COUNT(*) FROM users")
+	if users != 0 {
+		t.Error("data should be gone")
+	}
+
+	var fk bool
+	db.Get(&fk, "PRAGMA foreign_keys")
+	if !fk {
+		t.Error("foreign keys should be enabled again")
+	}
+}
@@ -0,0 +1,228 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// HookEvent es lo que reciben los hooks
+type HookEvent struct {
+	// Migration es la migración en curso en BeforeEach, AfterEach y OnError.
+	// En BeforeAll y AfterAll, y en OnError si el fallo no fue de una
+	// migración, es la zero value.
+	Migration Migration
+	// Direction está vacía en BeforeAll y AfterAll de Redo, que revierte y
+	// aplica
+	Direction Direction
+	// Duration es lo que tardó la migración en AfterEach y OnError, o toda la
+	// ejecución en AfterAll
+	Duration time.Duration
+	// Err es el error que disparó OnError
+	Err error
+	// Steps son las migraciones que terminaron hasta el momento
+	Steps []Step
+	// DB ejecuta sentencias en la misma sesión que la migración: su
+	// transacción en BeforeEach y AfterEach, o su conexión si corre sin
+	// transacción. En los demás hooks es la conexión del Migrator.
+	DB sqlx.ExecerContext
+}
+
+// HookFunc es un hook en Go. Si devuelve un error en BeforeAll, BeforeEach,
+// AfterEach o AfterAll la ejecución falla y se llama a OnError; el error de
+// OnError solo se registra en el Logger.
+type HookFunc func(ctx context.Context, e HookEvent) error
+
+// Hooks son funciones que Up, Down, Goto y Redo llaman alrededor de cada migración
+// y de toda la ejecución. Ninguno corre en dry-run.
+type Hooks struct {
+	// BeforeAll corre después de tomar el lock, antes de la primera migración
+	BeforeAll HookFunc
+	// BeforeEach corre dentro de la transacción de cada migración, antes del
+	// script
+	BeforeEach HookFunc
+	// AfterEach corre dentro de la transacción de cada migración, después del
+	// script
+	AfterEach HookFunc
+	// AfterAll corre cuando todas las migraciones terminaron bien, incluidas
+	// las repetibles
+	AfterAll HookFunc
+	// OnError corre cuando algo falla, incluidos los demás hooks, fuera de
+	// cualquier transacción
+	OnError HookFunc
+}
+
+// CallbacksDir es el subdirectorio de Options.Source con los callbacks SQL
+const CallbacksDir = "_callbacks"
+
+// Nombres de los callbacks SQL de CallbacksDir y el hook al que equivalen
+const (
+	CallbackBeforeMigrate     = "beforeMigrate.sql"     // BeforeAll
+	CallbackBeforeEachMigrate = "beforeEachMigrate.sql" // BeforeEach
+	CallbackAfterEachMigrate  = "afterEachMigrate.sql"  // AfterEach
+	CallbackAfterMigrate      = "afterMigrate.sql"      // AfterAll
+	CallbackAfterMigrateError = "afterMigrateError.sql" // OnError
+)
+
+var callbackNames = []string{
+	CallbackBeforeMigrate,
+	CallbackBeforeEachMigrate,
+	CallbackAfterEachMigrate,
+	CallbackAfterMigrate,
+	CallbackAfterMigrateError,
+}
+
+// LoadCallbacksFS lee los callbacks SQL de CallbacksDir en fsys, por nombre de
+// archivo. Que el directorio no exista no es un error; un archivo .sql con
+// otro nombre sí.
+func LoadCallbacksFS(fsys fs.FS) (map[string]string, error) {
+	files, err := fs.ReadDir(fsys, CallbacksDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	callbacks := map[string]string{}
+	var problems []FileError
+	for _, f := range files {
+		name := f.Name()
+		file := path.Join(CallbacksDir, name)
+		if f.IsDir() || path.Ext(name) != ".sql" {
+			continue
+		}
+		if !isCallback(name) {
+			problems = append(problems, FileError{file, "no es un callback conocido (beforeMigrate, beforeEachMigrate, afterEachMigrate, afterMigrate, afterMigrateError)"})
+			continue
+		}
+
+		sql, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			problems = append(problems, FileError{file, fmt.Sprintf("no se pudo leer: %v", err)})
+			continue
+		}
+		callbacks[name] = string(sql)
+	}
+
+	if len(problems) > 0 {
+		return nil, &LoadError{Files: problems}
+	}
+	return callbacks, nil
+}
+
+func isCallback(name string) bool {
+	for _, c := range callbackNames {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hooks combina los callbacks SQL con Options.Hooks para una ejecución. Un
+// *hooks nil no hace nada, como en dry-run.
+type hooks struct {
+	m   *Migrator
+	sql map[string]string
+	fns Hooks
+	// failed indica que OnError ya corrió en esta ejecución
+	failed bool
+}
+
+// hooks carga los callbacks SQL de Options.Source
+func (m *Migrator) hooks() (*hooks, error) {
+	h := &hooks{m: m, fns: m.opts.Hooks}
+	if m.opts.Source == nil {
+		return h, nil
+	}
+
+	callbacks, err := LoadCallbacksFS(m.opts.Source)
+	if err != nil {
+		return nil, fmt.Errorf("error cargando callbacks: %w", err)
+	}
+	h.sql = callbacks
+	return h, nil
+}
+
+// call ejecuta el callback SQL y después la función Go del mismo hook
+func (h *hooks) call(ctx context.Context, callback string, fn HookFunc, e HookEvent) error {
+	if script := h.sql[callback]; script != "" {
+		if err := execScript(ctx, e.DB, script, h.m.dialect); err != nil {
+			return fmt.Errorf("callback %s: %w", callback, err)
+		}
+	}
+	if fn != nil {
+		return fn(ctx, e)
+	}
+	return nil
+}
+
+func (h *hooks) beforeAll(ctx context.Context, dir Direction) error {
+	if h == nil {
+		return nil
+	}
+	if err := h.call(ctx, CallbackBeforeMigrate, h.fns.BeforeAll, HookEvent{Direction: dir, DB: h.m.db}); err != nil {
+		return fmt.Errorf("hook BeforeAll: %w", err)
+	}
+	return nil
+}
+
+// around corre BeforeEach, el script de la migración y AfterEach en la misma
+// sesión
+func (h *hooks) around(ctx context.Context, db sqlx.ExecerContext, mig Migration, dir Direction, run func() error) error {
+	if h == nil {
+		return run()
+	}
+
+	start := time.Now()
+	if err := h.call(ctx, CallbackBeforeEachMigrate, h.fns.BeforeEach, HookEvent{Migration: mig, Direction: dir, DB: db}); err != nil {
+		return &beforeEachError{err}
+	}
+	if err := run(); err != nil {
+		return err
+	}
+	if err := h.call(ctx, CallbackAfterEachMigrate, h.fns.AfterEach, HookEvent{Migration: mig, Direction: dir, Duration: time.Since(start), DB: db}); err != nil {
+		return fmt.Errorf("hook AfterEach: %w", err)
+	}
+	return nil
+}
+
+// beforeEachError indica que falló BeforeEach: el script de la migración no
+// llegó a correr
+type beforeEachError struct {
+	err error
+}
+
+func (e *beforeEachError) Error() string { return "hook BeforeEach: " + e.err.Error() }
+func (e *beforeEachError) Unwrap() error { return e.err }
+
+func (h *hooks) afterAll(ctx context.Context, dir Direction, d time.Duration, steps []Step) error {
+	if h == nil {
+		return nil
+	}
+	if err := h.call(ctx, CallbackAfterMigrate, h.fns.AfterAll, HookEvent{Direction: dir, Duration: d, Steps: steps, DB: h.m.db}); err != nil {
+		return fmt.Errorf("hook AfterAll: %w", err)
+	}
+	return nil
+}
+
+// onError corre una sola vez por ejecución, aunque ctx esté cancelado, y sus
+// errores solo se registran: el error que importa es el de la migración
+func (h *hooks) onError(ctx context.Context, e HookEvent) {
+	if h == nil || h.failed {
+		return
+	}
+	h.failed = true
+	e.DB = h.m.db
+	ctx = context.WithoutCancel(ctx)
+	if err := h.call(ctx, CallbackAfterMigrateError, h.fns.OnError, e); err != nil {
+		h.m.log(ctx, slog.LevelWarn, "falló el hook OnError", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,221 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHooks(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	var calls []string
+	record := func(name string) HookFunc {
+		return func(ctx context.Context, e HookEvent) error {
+			calls = append(calls, fmt.Sprintf("%s %d %s", name, e.Migration.Version, e.Direction))
+			if e.DB == nil {
+				t.Errorf("%s: DB is nil", name)
+			}
+			return nil
+		}
+	}
+
+	var afterAll, onError HookEvent
+	hooks := Hooks{
+		BeforeAll:  record("BeforeAll"),
+		BeforeEach: record("BeforeEach"),
+		AfterEach: func(ctx context.Context, e HookEvent) error {
+			calls = append(calls, fmt.Sprintf("AfterEach %d %s", e.Migration.Version, e.Direction))
+			// Corre en la transacción de la migración: ve la tabla recién creada
+			_, err := e.DB.ExecContext(ctx, "INSERT INTO audit (version) VALUES (?)", e.Migration.Version)
+			return err
+		},
+		AfterAll: func(ctx context.Context, e HookEvent) error {
+			afterAll = e
+			calls = append(calls, "AfterAll")
+			return nil
+		},
+		OnError: func(ctx context.Context, e HookEvent) error {
+			onError = e
+			calls = append(calls, fmt.Sprintf("OnError %d", e.Migration.Version))
+			return errors.New("slack caído")
+		},
+	}
+
+	source := fstest.MapFS{
+		"1_audit.up.sql": {Data: []byte("CREATE TABLE audit (version INTEGER);")},
+		"2_users.up.sql": {Data: []byte("CREATE TABLE users (id INTEGER);")},
+	}
+
+	t.Run("hook order", func(t *testing.T) {
+		m, _ := New(db, Options{Source: source, Hooks: hooks})
+		if _, err := m.Up(ctx); err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+
+		expected := []string{
+			"BeforeAll 0 up",
+			"BeforeEach 1 up", "AfterEach 1 up",
+			"BeforeEach 2 up", "AfterEach 2 up",
+			"AfterAll",
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(expected) {
+			t.Errorf("expected %v, got %v", expected, calls)
+		}
+		if len(afterAll.Steps) != 2 || afterAll.Duration <= 0 {
+			t.Errorf("unexpected AfterAll event: %+v", afterAll)
+		}
+
+		var audited int
+		db.Get(&audited, "SELECT COUNT(*) FROM audit")
+		if audited != 2 {
+			t.Errorf("expected 2 audit rows, got %d", audited)
+		}
+	})
+
+	t.Run("dry run does not call hooks", func(t *testing.T) {
+		calls = nil
+		source["3_posts.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE posts (id INTEGER);")}
+
+		m, _ := New(db, Options{Source: source, Hooks: hooks, DryRun: true})
+		if _, err := m.Up(ctx); err != nil {
+			t.Fatalf("dry-run Up failed: %v", err)
+		}
+		if len(calls) != 0 {
+			t.Errorf("expected no hooks in dry-run, got %v", calls)
+		}
+	})
+
+	t.Run("OnError receives the failed migration", func(t *testing.T) {
+		calls = nil
+		source["4_broken.up.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO missing VALUES (1);")}
+
+		m, _ := New(db, Options{Source: source, Hooks: hooks})
+		if _, err := m.Up(ctx); err == nil {
+			t.Fatal("expected error")
+		}
+
+		expected := []string{
+			"BeforeAll 0 up",
+			"BeforeEach 3 up", "AfterEach 3 up",
+			"BeforeEach 4 up",
+			"OnError 4",
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(expected) {
+			t.Errorf("expected %v, got %v", expected, calls)
+		}
+		if onError.Err == nil || len(onError.Steps) != 1 || onError.Direction != DirectionUp {
+			t.Errorf("unexpected OnError event: %+v", onError)
+		}
+	})
+
+	t.Run("BeforeEach error aborts the migration", func(t *testing.T) {
+		delete(source, "4_broken.up.sql")
+		failing := hooks
+		failing.BeforeEach = func(ctx context.Context, e HookEvent) error {
+			return errors.New("no")
+		}
+
+		m, _ := New(db, Options{Source: source, Hooks: failing})
+		source["5_tags.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE tags (id INTEGER);")}
+		if _, err := m.Up(ctx); err == nil {
+			t.Fatal("expected error")
+		}
+		if TableExists(t, db, "tags") {
+			t.Error("migration should not have run")
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2, 3})
+	})
+
+	t.Run("BeforeEach error does not leave a no-transaction migration dirty", func(t *testing.T) {
+		source["5_tags.up.sql"] = &fstest.MapFile{Data: []byte("-- migrate:no-transaction\nCREATE TABLE tags (id INTEGER);")}
+		failing := hooks
+		failing.BeforeEach = func(ctx context.Context, e HookEvent) error {
+			return errors.New("no")
+		}
+
+		m, _ := New(db, Options{Source: source, Hooks: failing})
+		_, err := m.Up(ctx)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if strings.Contains(err.Error(), "dirty") {
+			t.Errorf("error should not report a dirty migration: %v", err)
+		}
+		if _, dirty, _ := dirtyVersion(ctx, db, DefaultTableName); dirty {
+			t.Fatal("migration left dirty although its script never ran")
+		}
+
+		m, _ = New(db, Options{Source: source})
+		if _, err := m.Up(ctx); err != nil {
+			t.Fatalf("Up after the failed hook failed: %v", err)
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2, 3, 5})
+	})
+
+	t.Run("AfterAll error calls OnError", func(t *testing.T) {
+		calls = nil
+		onError = HookEvent{}
+		failing := hooks
+		failing.AfterAll = func(ctx context.Context, e HookEvent) error {
+			return errors.New("reporte caído")
+		}
+
+		m, _ := New(db, Options{Source: source, Hooks: failing})
+		if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "AfterAll") {
+			t.Fatalf("expected AfterAll error, got %v", err)
+		}
+		if onError.Err == nil || !strings.Contains(onError.Err.Error(), "reporte caído") {
+			t.Errorf("OnError should receive the AfterAll error, got %+v", onError)
+		}
+	})
+}
+
+func TestSQLCallbacks(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	source := fstest.MapFS{
+		"1_users.up.sql":                   {Data: []byte("CREATE TABLE users (id INTEGER);")},
+		"2_posts.up.sql":                   {Data: []byte("CREATE TABLE posts (id INTEGER);")},
+		"_callbacks/beforeMigrate.sql":     {Data: []byte("CREATE TABLE IF NOT EXISTS callback_log (event TEXT);\nINSERT INTO callback_log VALUES ('before');")},
+		"_callbacks/afterEachMigrate.sql":  {Data: []byte("INSERT INTO callback_log VALUES ('each');")},
+		"_callbacks/afterMigrate.sql":      {Data: []byte("INSERT INTO callback_log VALUES ('after');")},
+		"_callbacks/afterMigrateError.sql": {Data: []byte("INSERT INTO callback_log VALUES ('error');")},
+		"_callbacks/README.md":             {Data: []byte("ignorado")},
+	}
+
+	m, _ := New(db, Options{Source: source})
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	var events []string
+	db.Select(&events, "SELECT event FROM callback_log")
+	if fmt.Sprint(events) != "[before each each after]" {
+		t.Errorf("unexpected callbacks: %v", events)
+	}
+
+	source["3_broken.up.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO missing VALUES (1);")}
+	if _, err := m.Up(ctx); err == nil {
+		t.Fatal("expected error")
+	}
+	events = nil
+	db.Select(&events, "SELECT event FROM callback_log")
+	if fmt.Sprint(events) != "[before each each after before error]" {
+		t.Errorf("unexpected callbacks: %v", events)
+	}
+
+	// Un nombre de callback desconocido es un error de carga
+	source["_callbacks/afterAll.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	var loadErr *LoadError
+	if _, err := m.Up(ctx); !errors.As(err, &loadErr) {
+		t.Errorf("expected LoadError, got %v", err)
+	}
+}
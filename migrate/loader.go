@@ -1,56 +1,150 @@
 package migrate
 
 import (
-	"log"
+	"fmt"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 )
 
+// LoadOptions configura la validación que hace el loader
+type LoadOptions struct {
+	// RequireDown hace que una migración up sin su archivo down sea un error.
+	// Un archivo down sin su up es siempre un error.
+	RequireDown bool
+}
+
+// FileError describe un archivo de migración inválido
+type FileError struct {
+	File   string
+	Reason string
+}
+
+// LoadError agrupa todos los archivos inválidos encontrados por el loader
+type LoadError struct {
+	Files []FileError
+}
+
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d archivo(s) de migración inválido(s):", len(e.Files))
+	for _, f := range e.Files {
+		fmt.Fprintf(&b, "\n  - %s: %s", f.File, f.Reason)
+	}
+	return b.String()
+}
+
+// migrationFile reconoce <version>_<nombre>.(up|down).sql
+var migrationFile = regexp.MustCompile(`^([0-9]+)_(.+)\.(up|down)\.sql$`)
+
+// repeatableFile reconoce las migraciones repetibles R_<nombre>.sql y sus
+// scripts de limpieza R_<nombre>.down.sql
+var repeatableFile = regexp.MustCompile(`^R_([^.]+)(\.down)?\.sql$`)
+
+// Load lee las migraciones de un directorio del sistema de archivos
 func Load(dir string) ([]Migration, error) {
-	files, err := os.ReadDir(dir)
+	return LoadFS(os.DirFS(dir))
+}
+
+// LoadFS lee las migraciones de la raíz de un fs.FS (por ejemplo un embed.FS).
+// Si los archivos están en un subdirectorio usá fs.Sub antes de llamarla.
+func LoadFS(fsys fs.FS) ([]Migration, error) {
+	return LoadFSWithOptions(fsys, LoadOptions{})
+}
+
+// LoadFSWithOptions lee y valida las migraciones de fsys. Si algún archivo
+// .sql es inválido devuelve un *LoadError con todos los problemas encontrados.
+func LoadFSWithOptions(fsys fs.FS, opts LoadOptions) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return nil, err
 	}
 
 	m := map[int]*Migration{}
+	// Archivos que definieron cada versión, para reportar duplicados y pares incompletos
+	upFiles := map[int]string{}
+	downFiles := map[int]string{}
+	var problems []FileError
 
 	for _, f := range files {
 		name := f.Name()
-		if !strings.HasSuffix(name, ".sql") {
+		if f.IsDir() || !strings.HasSuffix(name, ".sql") || repeatableFile.MatchString(name) {
 			continue
 		}
 
-		parts := strings.Split(name, "_")
-		version, _ := strconv.Atoi(parts[0])
-		full := filepath.Join(dir, name)
-		sql, err := os.ReadFile(full)
-		if err != nil {
-			log.Printf("error: %v", err)
+		parts := migrationFile.FindStringSubmatch(name)
+		if parts == nil {
+			problems = append(problems, FileError{name, "el nombre no respeta el formato <version>_<nombre>.(up|down).sql"})
+			continue
+		}
+
+		version, err := strconv.Atoi(parts[1])
+		if err != nil || version < 1 {
+			problems = append(problems, FileError{name, "la versión debe ser un entero mayor a 0"})
 			continue
 		}
+		migrationName, direction := parts[2], parts[3]
+
 		entry, ok := m[version]
 		if !ok {
-			// Extraer el nombre de la migración del nombre del archivo
-			nameParts := parts[1:]
-			migrationName := ""
-			if len(nameParts) > 0 {
-				migrationName = strings.TrimSuffix(strings.Join(nameParts, "_"), ".up.sql")
-				migrationName = strings.TrimSuffix(migrationName, ".down.sql")
-			}
 			entry = &Migration{Version: version, Name: migrationName}
 			m[version] = entry
+		} else if entry.Name != migrationName {
+			problems = append(problems, FileError{name, fmt.Sprintf("la versión %d ya está usada por la migración %q", version, entry.Name)})
+			continue
+		}
+
+		// 0001_foo.up.sql y 1_foo.up.sql son la misma migración
+		defined := upFiles
+		if direction == "down" {
+			defined = downFiles
+		}
+		if other, ok := defined[version]; ok {
+			problems = append(problems, FileError{name, fmt.Sprintf("la versión %d ya está definida por %s", version, other)})
+			continue
 		}
 
-		if strings.Contains(name, ".up.") {
+		sql, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			problems = append(problems, FileError{name, fmt.Sprintf("no se pudo leer: %v", err)})
+			continue
+		}
+
+		_, noTx := headerDirectives(string(sql))["no-transaction"]
+		if direction == "up" {
 			entry.UpSQL = string(sql)
-		} else if strings.Contains(name, ".down.") {
+			entry.UpNoTransaction = noTx
+			upFiles[version] = name
+		} else {
 			entry.DownSQL = string(sql)
+			entry.DownNoTransaction = noTx
+			downFiles[version] = name
 		}
 	}
 
+	for version, name := range downFiles {
+		if _, ok := upFiles[version]; !ok {
+			problems = append(problems, FileError{name, "no tiene su archivo up"})
+		}
+	}
+	if opts.RequireDown {
+		for version, name := range upFiles {
+			if _, ok := downFiles[version]; !ok {
+				problems = append(problems, FileError{name, "no tiene su archivo down"})
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.SliceStable(problems, func(i, j int) bool {
+			return problems[i].File < problems[j].File
+		})
+		return nil, &LoadError{Files: problems}
+	}
+
 	var out []Migration
 	for _, v := range m {
 		out = append(out, *v)
@@ -62,3 +156,94 @@ func Load(dir string) ([]Migration, error) {
 
 	return out, nil
 }
+
+// LoadRepeatables lee las migraciones repetibles de un directorio
+func LoadRepeatables(dir string) ([]Repeatable, error) {
+	return LoadRepeatablesFS(os.DirFS(dir))
+}
+
+// LoadRepeatablesFS lee las migraciones repetibles R_<nombre>.sql de la raíz
+// de fsys, ordenadas por nombre, junto con sus R_<nombre>.down.sql
+func LoadRepeatablesFS(fsys fs.FS) ([]Repeatable, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*Repeatable{}
+	// Archivos de cada parte, para reportar limpiezas sin su repetible
+	ups := map[string]bool{}
+	downs := map[string]string{}
+	var problems []FileError
+	for _, f := range files {
+		parts := repeatableFile.FindStringSubmatch(f.Name())
+		if f.IsDir() || parts == nil {
+			continue
+		}
+
+		sql, err := fs.ReadFile(fsys, f.Name())
+		if err != nil {
+			problems = append(problems, FileError{f.Name(), fmt.Sprintf("no se pudo leer: %v", err)})
+			continue
+		}
+		_, noTx := headerDirectives(string(sql))["no-transaction"]
+
+		r := byName[parts[1]]
+		if r == nil {
+			r = &Repeatable{Name: parts[1]}
+			byName[parts[1]] = r
+		}
+		if parts[2] != "" {
+			r.DownSQL, r.DownNoTransaction = string(sql), noTx
+			downs[parts[1]] = f.Name()
+		} else {
+			r.SQL, r.NoTransaction = string(sql), noTx
+			ups[parts[1]] = true
+		}
+	}
+
+	var out []Repeatable
+	for name, r := range byName {
+		if !ups[name] {
+			problems = append(problems, FileError{downs[name], fmt.Sprintf("no tiene su archivo R_%s.sql", name)})
+			continue
+		}
+		out = append(out, *r)
+	}
+	if len(problems) > 0 {
+		sort.Slice(problems, func(i, j int) bool {
+			return problems[i].File < problems[j].File
+		})
+		return nil, &LoadError{Files: problems}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// headerDirectives lee las directivas "-- migrate:<nombre> [valor]" de los
+// comentarios al principio del archivo. La lectura termina en la primera
+// línea que no es un comentario ni está vacía.
+func headerDirectives(sql string) map[string]string {
+	directives := map[string]string{}
+	for _, line := range strings.Split(sql, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		comment := strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		directive, ok := strings.CutPrefix(comment, "migrate:")
+		if !ok {
+			continue
+		}
+		name, value, _ := strings.Cut(directive, " ")
+		directives[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return directives
+}
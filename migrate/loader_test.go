@@ -1,9 +1,12 @@
 package migrate
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestLoad(t *testing.T) {
@@ -243,36 +246,130 @@ CREATE TABLE test (
 }
 
 func TestLoadWithInvalidFileNames(t *testing.T) {
-	dir := t.TempDir()
-	
-	t.Run("file without version number", func(t *testing.T) {
-		CreateMigrationFile(t, dir, "no_version.up.sql", "SELECT 1;")
-		
-		migrations, err := Load(dir)
-		if err != nil {
-			t.Fatalf("Load failed: %v", err)
+	// loadErrorFiles exige un *LoadError y devuelve los archivos reportados
+	loadErrorFiles := func(t *testing.T, err error) []string {
+		t.Helper()
+
+		var loadErr *LoadError
+		if !errors.As(err, &loadErr) {
+			t.Fatalf("expected *LoadError, got %v", err)
 		}
-		
-		// Debe tener version 0 (atoi falla y retorna 0)
-		if len(migrations) != 1 {
-			t.Fatalf("expected 1 migration, got %d", len(migrations))
+
+		var files []string
+		for _, f := range loadErr.Files {
+			files = append(files, f.File)
 		}
-		if migrations[0].Version != 0 {
-			t.Errorf("expected version 0, got %d", migrations[0].Version)
+		return files
+	}
+
+	t.Run("file without version number", func(t *testing.T) {
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "no_version.up.sql", "SELECT 1;")
+
+		_, err := Load(dir)
+		files := loadErrorFiles(t, err)
+		if len(files) != 1 || files[0] != "no_version.up.sql" {
+			t.Errorf("unexpected invalid files: %v", files)
 		}
 	})
-	
+
 	t.Run("file without underscore separator", func(t *testing.T) {
 		dir := t.TempDir()
 		CreateMigrationFile(t, dir, "1.up.sql", "SELECT 1;")
-		
-		migrations, err := Load(dir)
-		if err != nil {
+
+		_, err := Load(dir)
+		loadErrorFiles(t, err)
+	})
+
+	t.Run("sql files without direction", func(t *testing.T) {
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "README.sql", "-- docs")
+		CreateMigrationFile(t, dir, "seed_users.sql", "INSERT INTO users VALUES (1);")
+		CreateMigrationFile(t, dir, "17x_foo.up.sql", "SELECT 1;")
+		CreateMigrationFile(t, dir, "18_bar.sql", "SELECT 1;")
+
+		_, err := Load(dir)
+		files := loadErrorFiles(t, err)
+
+		// Todos los archivos inválidos se reportan juntos, ordenados
+		expected := []string{"17x_foo.up.sql", "18_bar.sql", "README.sql", "seed_users.sql"}
+		if len(files) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, files)
+		}
+		for i := range expected {
+			if files[i] != expected[i] {
+				t.Errorf("file %d: expected %s, got %s", i, expected[i], files[i])
+			}
+		}
+	})
+
+	t.Run("duplicate version with different names", func(t *testing.T) {
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "1_create_users.up.sql", "SELECT 1;")
+		CreateMigrationFile(t, dir, "1_create_posts.up.sql", "SELECT 2;")
+
+		_, err := Load(dir)
+		files := loadErrorFiles(t, err)
+		if len(files) != 1 {
+			t.Errorf("expected 1 invalid file, got %v", files)
+		}
+	})
+
+	t.Run("same version and name with different padding", func(t *testing.T) {
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "0001_foo.up.sql", "SELECT 1;")
+		CreateMigrationFile(t, dir, "1_foo.up.sql", "SELECT 2;")
+		CreateMigrationFile(t, dir, "1_foo.down.sql", "SELECT 3;")
+
+		_, err := Load(dir)
+		files := loadErrorFiles(t, err)
+		if len(files) != 1 || files[0] != "1_foo.up.sql" {
+			t.Errorf("unexpected invalid files: %v", files)
+		}
+		if !strings.Contains(err.Error(), "ya está definida por 0001_foo.up.sql") {
+			t.Errorf("error should name the first file: %v", err)
+		}
+	})
+
+	t.Run("down without up", func(t *testing.T) {
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "1_test.up.sql", "SELECT 1;")
+		CreateMigrationFile(t, dir, "2_orphan.down.sql", "SELECT 2;")
+
+		_, err := Load(dir)
+		files := loadErrorFiles(t, err)
+		if len(files) != 1 || files[0] != "2_orphan.down.sql" {
+			t.Errorf("unexpected invalid files: %v", files)
+		}
+	})
+
+	t.Run("version zero", func(t *testing.T) {
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "0_init.up.sql", "SELECT 1;")
+
+		_, err := Load(dir)
+		loadErrorFiles(t, err)
+	})
+
+	t.Run("missing down allowed by default", func(t *testing.T) {
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "1_test.up.sql", "SELECT 1;")
+
+		if _, err := Load(dir); err != nil {
 			t.Fatalf("Load failed: %v", err)
 		}
-		
-		if len(migrations) != 1 {
-			t.Fatalf("expected 1 migration, got %d", len(migrations))
+	})
+
+	t.Run("missing down rejected with RequireDown", func(t *testing.T) {
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "1_test.up.sql", "SELECT 1;")
+		CreateMigrationFile(t, dir, "2_other.up.sql", "SELECT 2;")
+		CreateMigrationFile(t, dir, "2_other.down.sql", "SELECT 3;")
+
+		_, err := LoadFSWithOptions(os.DirFS(dir), LoadOptions{RequireDown: true})
+		files := loadErrorFiles(t, err)
+		if len(files) != 1 || files[0] != "1_test.up.sql" {
+			t.Errorf("unexpected invalid files: %v", files)
 		}
 	})
 }
@@ -309,3 +406,67 @@ func TestLoadPermissions(t *testing.T) {
 		}
 	})
 }
+
+func TestLoadFS(t *testing.T) {
+	t.Run("load from in-memory fs", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"2_add_posts.up.sql":      {Data: []byte("CREATE TABLE posts;")},
+			"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users;")},
+			"1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+			"README.md":               {Data: []byte("# Migrations")},
+		}
+
+		migrations, err := LoadFS(fsys)
+		if err != nil {
+			t.Fatalf("LoadFS failed: %v", err)
+		}
+
+		if len(migrations) != 2 {
+			t.Fatalf("expected 2 migrations, got %d", len(migrations))
+		}
+		if migrations[0].Version != 1 || migrations[0].Name != "create_users" {
+			t.Errorf("unexpected first migration: %d %s", migrations[0].Version, migrations[0].Name)
+		}
+		if migrations[0].DownSQL != "DROP TABLE users;" {
+			t.Errorf("down SQL not loaded: %s", migrations[0].DownSQL)
+		}
+	})
+
+	t.Run("subdirectories are ignored", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"1_test.up.sql":         {Data: []byte("SELECT 1;")},
+			"nested/2_other.up.sql": {Data: []byte("SELECT 2;")},
+		}
+
+		migrations, err := LoadFS(fsys)
+		if err != nil {
+			t.Fatalf("LoadFS failed: %v", err)
+		}
+
+		if len(migrations) != 1 {
+			t.Errorf("expected 1 migration, got %d", len(migrations))
+		}
+	})
+}
+
+func TestLoadNoTransactionDirective(t *testing.T) {
+	dir := t.TempDir()
+	CreateMigrationFile(t, dir, "1_vacuum.up.sql", "-- Compactar la base\n-- migrate:no-transaction\n\nVACUUM;\n")
+	CreateMigrationFile(t, dir, "1_vacuum.down.sql", "SELECT 1;")
+	CreateMigrationFile(t, dir, "2_late.up.sql", "SELECT 1;\n-- migrate:no-transaction\n")
+
+	migrations, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if !migrations[0].UpNoTransaction {
+		t.Error("directive in header was not parsed")
+	}
+	if migrations[0].DownNoTransaction {
+		t.Error("down file has no directive")
+	}
+	if migrations[1].UpNoTransaction {
+		t.Error("directive after the first statement must be ignored")
+	}
+}
@@ -2,9 +2,13 @@ package migrate
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"os"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -18,30 +22,89 @@ type Locker interface {
 	Unlock() error
 }
 
-// NewLocker crea un locker apropiado según el driver de la base de datos
+// ErrLocked indica que otro proceso tiene el lock y no se liberó antes de que
+// venciera el contexto
+var ErrLocked = errors.New("no se pudo adquirir el lock, otra migración está en progreso")
+
+// Espera entre intentos de adquirir un lock tomado; se duplica en cada intento
+const (
+	lockRetryMin = 50 * time.Millisecond
+	lockRetryMax = 2 * time.Second
+)
+
+// waitLock reintenta try con backoff exponencial hasta que adquiere el lock o
+// hasta que termina ctx
+func waitLock(ctx context.Context, try func(context.Context) (bool, error)) error {
+	start := time.Now()
+	delay := lockRetryMin
+
+	for {
+		acquired, err := try(ctx)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (se esperó %s): %w", ErrLocked, time.Since(start).Round(time.Millisecond), ctx.Err())
+		case <-timer.C:
+		}
+		delay = min(delay*2, lockRetryMax)
+	}
+}
+
+// DefaultLockKey es la clave que usa NewLocker. Dos lockers con la misma
+// clave sobre el mismo servidor se excluyen entre sí.
+const DefaultLockKey = "migrator_lock"
+
+// NewLocker crea un locker apropiado según el driver de la base de datos,
+// con la clave DefaultLockKey
 func NewLocker(db *sqlx.DB) (Locker, error) {
+	return NewLockerWithKey(db, DefaultLockKey)
+}
+
+// NewLockerWithKey crea un locker apropiado según el driver de la base de
+// datos que se bloquea sobre key. Para drivers sin un mecanismo propio usa
+// TableLocker.
+func NewLockerWithKey(db *sqlx.DB, key string) (Locker, error) {
 	driver := db.DriverName()
 
 	switch driver {
 	case "postgres":
-		return &PostgresLocker{db: db}, nil
+		return NewPostgresLocker(db, key), nil
 	case "mysql":
-		return &MySQLLocker{db: db}, nil
+		return NewMySQLLocker(db, key), nil
 	case "sqlite3":
-		return &SQLiteLocker{db: db}, nil
+		return NewSQLiteLocker(db, key), nil
 	default:
-		return nil, fmt.Errorf("driver no soportado para locking: %s", driver)
+		return NewTableLocker(db, key), nil
 	}
 }
 
 // PostgresLocker usa advisory locks de PostgreSQL
 type PostgresLocker struct {
 	db     *sqlx.DB
+	key    string
 	conn   *sql.Conn
 	locked bool
 }
 
-const pgLockKey = 0x6D696772617465 // "migrate" en hex
+// NewPostgresLocker crea un locker sobre el advisory lock derivado de key
+func NewPostgresLocker(db *sqlx.DB, key string) *PostgresLocker {
+	return &PostgresLocker{db: db, key: key}
+}
+
+// advisoryKey convierte la clave en el bigint que esperan los advisory locks
+func advisoryKey(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
 
 func (l *PostgresLocker) Lock(ctx context.Context) error {
 	if l.locked {
@@ -55,18 +118,18 @@ func (l *PostgresLocker) Lock(ctx context.Context) error {
 	}
 	l.conn = conn
 
-	// Intentar adquirir el lock con timeout
-	var acquired bool
-	query := `SELECT pg_try_advisory_lock($1)`
-	
-	if err := conn.QueryRowContext(ctx, query, pgLockKey).Scan(&acquired); err != nil {
-		conn.Close()
-		return fmt.Errorf("error adquiriendo lock: %w", err)
-	}
-
-	if !acquired {
+	// Reintentar hasta que se libere o venza el contexto
+	err = waitLock(ctx, func(ctx context.Context) (bool, error) {
+		var acquired bool
+		query := `SELECT pg_try_advisory_lock($1)`
+		if err := conn.QueryRowContext(ctx, query, advisoryKey(l.key)).Scan(&acquired); err != nil {
+			return false, fmt.Errorf("error adquiriendo lock: %w", err)
+		}
+		return acquired, nil
+	})
+	if err != nil {
 		conn.Close()
-		return errors.New("no se pudo adquirir el lock, otra migración está en progreso")
+		return err
 	}
 
 	l.locked = true
@@ -82,7 +145,7 @@ func (l *PostgresLocker) Unlock() error {
 
 	var released bool
 	query := `SELECT pg_advisory_unlock($1)`
-	if err := l.conn.QueryRowContext(context.Background(), query, pgLockKey).Scan(&released); err != nil {
+	if err := l.conn.QueryRowContext(context.Background(), query, advisoryKey(l.key)).Scan(&released); err != nil {
 		return fmt.Errorf("error liberando lock: %w", err)
 	}
 
@@ -93,11 +156,26 @@ func (l *PostgresLocker) Unlock() error {
 // MySQLLocker usa GET_LOCK() de MySQL
 type MySQLLocker struct {
 	db     *sqlx.DB
+	key    string
 	conn   *sql.Conn
 	locked bool
 }
 
-const mysqlLockName = "migrator_lock"
+// NewMySQLLocker crea un locker sobre el lock con nombre key. GET_LOCK es
+// global al servidor, así que la clave debería incluir el nombre de la base.
+func NewMySQLLocker(db *sqlx.DB, key string) *MySQLLocker {
+	return &MySQLLocker{db: db, key: key}
+}
+
+// mysqlLockName acorta las claves que superan los 64 caracteres que admite
+// GET_LOCK
+func mysqlLockName(key string) string {
+	if len(key) <= 64 {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
 
 func (l *MySQLLocker) Lock(ctx context.Context) error {
 	if l.locked {
@@ -111,18 +189,18 @@ func (l *MySQLLocker) Lock(ctx context.Context) error {
 	}
 	l.conn = conn
 
-	// Intentar adquirir el lock con timeout de 10 segundos
-	var result sql.NullInt64
-	query := `SELECT GET_LOCK(?, 10)`
-	
-	if err := conn.QueryRowContext(ctx, query, mysqlLockName).Scan(&result); err != nil {
-		conn.Close()
-		return fmt.Errorf("error adquiriendo lock: %w", err)
-	}
-
-	if !result.Valid || result.Int64 != 1 {
+	// GET_LOCK con timeout 0 no bloquea; la espera la controla el contexto
+	err = waitLock(ctx, func(ctx context.Context) (bool, error) {
+		var result sql.NullInt64
+		query := `SELECT GET_LOCK(?, 0)`
+		if err := conn.QueryRowContext(ctx, query, mysqlLockName(l.key)).Scan(&result); err != nil {
+			return false, fmt.Errorf("error adquiriendo lock: %w", err)
+		}
+		return result.Valid && result.Int64 == 1, nil
+	})
+	if err != nil {
 		conn.Close()
-		return errors.New("no se pudo adquirir el lock, otra migración está en progreso")
+		return err
 	}
 
 	l.locked = true
@@ -138,7 +216,7 @@ func (l *MySQLLocker) Unlock() error {
 
 	var result sql.NullInt64
 	query := `SELECT RELEASE_LOCK(?)`
-	if err := l.conn.QueryRowContext(context.Background(), query, mysqlLockName).Scan(&result); err != nil {
+	if err := l.conn.QueryRowContext(context.Background(), query, mysqlLockName(l.key)).Scan(&result); err != nil {
 		return fmt.Errorf("error liberando lock: %w", err)
 	}
 
@@ -146,76 +224,88 @@ func (l *MySQLLocker) Unlock() error {
 	return nil
 }
 
-// SQLiteLocker usa una tabla de bloqueo con transacción exclusiva
+// SQLiteLocker usa una fila en una tabla de bloqueo. No mantiene una
+// transacción abierta: SQLite admite un solo escritor y las migraciones
+// necesitan escribir desde otras conexiones mientras el lock está tomado.
 type SQLiteLocker struct {
-	db     *sqlx.DB
-	tx     *sql.Tx
+	db  *sqlx.DB
+	key string
+	// owner identifica a este locker en la fila del lock, aunque otro locker
+	// del mismo proceso la tome después de un ForceRelease
+	owner  string
 	locked bool
 }
 
+// NewSQLiteLocker crea un locker sobre la tabla migration_lock. La base es un
+// único archivo con un solo escritor, así que hay un único lock por base y la
+// clave no separa lockers.
+func NewSQLiteLocker(db *sqlx.DB, key string) *SQLiteLocker {
+	return &SQLiteLocker{db: db, key: key}
+}
+
 func (l *SQLiteLocker) Lock(ctx context.Context) error {
 	if l.locked {
 		return errors.New("ya está bloqueado")
 	}
 
-	// Crear tabla de locks si no existe
-	_, err := l.db.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS migration_lock (
-			id INTEGER PRIMARY KEY CHECK (id = 1),
-			locked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("error creando tabla de lock: %w", err)
+	if err := l.ensureTable(ctx); err != nil {
+		return err
 	}
 
-	// Iniciar transacción exclusiva
-	tx, err := l.db.BeginTx(ctx, &sql.TxOptions{
-		Isolation: sql.LevelSerializable,
-	})
+	owner, err := newOwnerID()
 	if err != nil {
-		return fmt.Errorf("error iniciando transacción: %w", err)
+		return fmt.Errorf("error generando owner del lock: %w", err)
 	}
-	l.tx = tx
 
-	// Intentar insertar el lock
-	result, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO migration_lock (id) VALUES (1)`)
+	// El proceso y el host quedan en la fila para lock status
+	host, _ := os.Hostname()
+	pid := os.Getpid()
+
+	err = waitLock(ctx, func(ctx context.Context) (bool, error) {
+		// Intentar insertar el lock; el INSERT es atómico en SQLite
+		result, err := l.db.ExecContext(ctx, `INSERT OR IGNORE INTO migration_lock (id, pid, host, owner) VALUES (1, ?, ?, ?)`, pid, host, owner)
+		if err != nil {
+			return false, fmt.Errorf("error adquiriendo lock: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("error verificando lock: %w", err)
+		}
+
+		// Si no se insertó ninguna fila, significa que ya existe el lock
+		return rows == 1, nil
+	})
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error adquiriendo lock: %w", err)
+		return err
 	}
 
-	rows, err := result.RowsAffected()
+	l.owner = owner
+	l.locked = true
+	return nil
+}
+
+// ensureTable crea la tabla de locks si no existe
+func (l *SQLiteLocker) ensureTable(ctx context.Context) error {
+	_, err := l.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS migration_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			locked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			pid INTEGER,
+			host TEXT,
+			owner TEXT
+		)
+	`)
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error verificando lock: %w", err)
+		return fmt.Errorf("error creando tabla de lock: %w", err)
 	}
 
-	// Si no se insertó ninguna fila, significa que ya existe el lock
-	if rows == 0 {
-		// Intentar actualizar con un timeout
-		done := make(chan error, 1)
-		go func() {
-			_, err := tx.ExecContext(ctx, `UPDATE migration_lock SET locked_at = CURRENT_TIMESTAMP WHERE id = 1`)
-			done <- err
-		}()
-
-		select {
-		case err := <-done:
-			if err != nil {
-				tx.Rollback()
-				return fmt.Errorf("no se pudo adquirir el lock: %w", err)
-			}
-		case <-time.After(10 * time.Second):
-			tx.Rollback()
-			return errors.New("timeout esperando lock, otra migración está en progreso")
-		case <-ctx.Done():
-			tx.Rollback()
-			return ctx.Err()
+	// Tablas creadas por versiones anteriores no tienen pid, host ni owner
+	for _, column := range []struct{ name, definition string }{{"pid", "INTEGER"}, {"host", "TEXT"}, {"owner", "TEXT"}} {
+		if err := ensureColumn(ctx, l.db, "migration_lock", column.name, column.definition); err != nil {
+			return fmt.Errorf("error actualizando tabla de lock: %w", err)
 		}
 	}
-
-	l.locked = true
 	return nil
 }
 
@@ -224,15 +314,10 @@ func (l *SQLiteLocker) Unlock() error {
 		return nil
 	}
 
-	// Commit de la transacción libera el lock
-	if err := l.tx.Commit(); err != nil {
-		return fmt.Errorf("error liberando lock: %w", err)
-	}
-
-	// Limpiar el registro de lock
-	_, err := l.db.Exec(`DELETE FROM migration_lock WHERE id = 1`)
+	// Limpiar el registro de lock, salvo que ya sea de otro locker
+	_, err := l.db.Exec(`DELETE FROM migration_lock WHERE id = 1 AND owner = ?`, l.owner)
 	if err != nil {
-		return fmt.Errorf("error limpiando lock: %w", err)
+		return fmt.Errorf("error liberando lock: %w", err)
 	}
 
 	l.locked = false
@@ -1,9 +1,16 @@
 package migrate
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 func TestNewLocker(t *testing.T) {
@@ -26,10 +33,17 @@ func TestNewLocker(t *testing.T) {
 		}
 	})
 	
-	t.Run("unsupported driver", func(t *testing.T) {
-		// No podemos fácilmente crear un driver no soportado en test
-		// pero el código está ahí para cuando se necesite
-		t.Skip("requires mock DB with unsupported driver")
+	t.Run("unknown driver falls back to TableLocker", func(t *testing.T) {
+		db := SetupTestDB(t)
+		defer db.Close()
+
+		locker, err := NewLocker(sqlx.NewDb(db.DB, "custom"))
+		if err != nil {
+			t.Fatalf("NewLocker failed: %v", err)
+		}
+		if _, ok := locker.(*TableLocker); !ok {
+			t.Errorf("expected TableLocker for unknown driver, got %T", locker)
+		}
 	})
 }
 
@@ -344,3 +358,161 @@ func BenchmarkLockUnlock(b *testing.B) {
 		locker.Unlock()
 	}
 }
+
+func TestNewLockerWithKey(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	locker, err := NewLockerWithKey(db, "billing/schema_migrations")
+	if err != nil {
+		t.Fatalf("NewLockerWithKey failed: %v", err)
+	}
+
+	sqlite, ok := locker.(*SQLiteLocker)
+	if !ok {
+		t.Fatalf("expected SQLiteLocker, got %T", locker)
+	}
+	if sqlite.key != "billing/schema_migrations" {
+		t.Errorf("unexpected key: %s", sqlite.key)
+	}
+}
+
+func TestLockKeyEncoding(t *testing.T) {
+	t.Run("advisory key is stable and depends on the key", func(t *testing.T) {
+		if advisoryKey("a/schema_migrations") != advisoryKey("a/schema_migrations") {
+			t.Error("advisory key should be deterministic")
+		}
+		if advisoryKey("a/schema_migrations") == advisoryKey("b/schema_migrations") {
+			t.Error("different keys should produce different advisory keys")
+		}
+	})
+
+	t.Run("mysql lock name fits GET_LOCK", func(t *testing.T) {
+		if name := mysqlLockName("shop/schema_migrations"); name != "shop/schema_migrations" {
+			t.Errorf("short keys should be kept, got %s", name)
+		}
+
+		long := strings.Repeat("x", 100)
+		if name := mysqlLockName(long); len(name) > 64 || name == mysqlLockName(long+"y") {
+			t.Errorf("unexpected lock name for long key: %s", name)
+		}
+	})
+}
+
+func TestMigratorLockKey(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	cases := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{}, "schema_migrations"},
+		{Options{TableName: "billing_migrations", Schema: "main"}, "main.billing_migrations"},
+		{Options{LockKey: "custom"}, "custom"},
+	}
+
+	for _, tc := range cases {
+		m, err := New(db, tc.opts)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+
+		key, err := m.lockKey(ctx)
+		if err != nil {
+			t.Fatalf("lockKey failed: %v", err)
+		}
+		if key != tc.expected {
+			t.Errorf("expected key %s, got %s", tc.expected, key)
+		}
+	}
+}
+
+func TestLockWaitsForRelease(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	holder, _ := NewLocker(db)
+	if err := holder.Lock(context.Background()); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		holder.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	waiter, _ := NewLocker(db)
+	start := time.Now()
+	if err := waiter.Lock(ctx); err != nil {
+		t.Fatalf("Lock should wait for the release: %v", err)
+	}
+	defer waiter.Unlock()
+
+	if time.Since(start) < 150*time.Millisecond {
+		t.Error("lock was acquired while still held")
+	}
+}
+
+func TestLockWaitTimeout(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	holder, _ := NewLocker(db)
+	if err := holder.Lock(context.Background()); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	defer holder.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	waiter, _ := NewLocker(db)
+	err := waiter.Lock(ctx)
+	if !errors.Is(err, ErrLocked) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected ErrLocked after the deadline, got %v", err)
+	}
+}
+
+func TestMigratorLogsLockWait(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	interval := lockWaitLogInterval
+	lockWaitLogInterval = 20 * time.Millisecond
+	defer func() { lockWaitLogInterval = interval }()
+
+	holder, _ := NewLocker(db)
+	if err := holder.Lock(context.Background()); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	defer holder.Unlock()
+
+	var buf bytes.Buffer
+	m, _ := New(db, Options{
+		Source:      os.DirFS(SetupTestMigrations(t)),
+		LockTimeout: 150 * time.Millisecond,
+		Logger:      SlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+	})
+
+	if _, err := m.Up(context.Background()); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+
+	waiting := 0
+	for _, r := range logRecords(t, &buf) {
+		if r["msg"] == "esperando el lock de migraciones" {
+			waiting++
+			if r["lock_key"] != "schema_migrations" {
+				t.Errorf("unexpected lock key: %v", r)
+			}
+		}
+	}
+	if waiting == 0 {
+		t.Error("lock wait was not logged")
+	}
+}
@@ -0,0 +1,282 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// LockInfo describe el estado del lock de migraciones
+type LockInfo struct {
+	Key  string
+	Held bool
+	// PID identifica a quien tiene el lock: el backend en PostgreSQL, el id de
+	// conexión en MySQL y el proceso del sistema operativo en SQLite
+	PID  int
+	Host string
+	// AcquiredAt es cero si el driver no informa cuándo se tomó el lock
+	AcquiredAt time.Time
+}
+
+// LockInspector lo implementan los lockers que pueden informar quién tiene el
+// lock y liberarlo desde otro proceso
+type LockInspector interface {
+	// Inspect devuelve el estado actual del lock
+	Inspect(ctx context.Context) (LockInfo, error)
+	// ForceRelease libera el lock aunque lo tenga otro proceso
+	ForceRelease(ctx context.Context) error
+}
+
+// ErrLockLost indica que el lock se perdió mientras se migraba, por ejemplo
+// porque se cortó la conexión que lo tenía. La migración en curso se cancela.
+var ErrLockLost = errors.New("se perdió el lock de migraciones")
+
+// lockLost marca el error con ErrLockLost si ctx se canceló porque se perdió
+// el lock
+func lockLost(ctx context.Context, err error) error {
+	if err != nil && errors.Is(context.Cause(ctx), ErrLockLost) && !errors.Is(err, ErrLockLost) {
+		return fmt.Errorf("%w: %w", ErrLockLost, err)
+	}
+	return err
+}
+
+// LockMonitor lo implementan los lockers que pueden comprobar si el lock que
+// tomaron sigue siendo suyo
+type LockMonitor interface {
+	// Held devuelve false si el lock se perdió. Un error indica que no se
+	// pudo comprobar, no que se haya perdido.
+	Held(ctx context.Context) (bool, error)
+}
+
+// LockStatus informa si el lock de este Migrator está tomado y por quién
+func (m *Migrator) LockStatus(ctx context.Context) (LockInfo, error) {
+	inspector, err := m.lockInspector(ctx)
+	if err != nil {
+		return LockInfo{}, err
+	}
+	return inspector.Inspect(ctx)
+}
+
+// ReleaseLock libera a la fuerza el lock de este Migrator, por ejemplo cuando
+// el proceso que lo tenía murió. Devuelve el estado que tenía el lock. Si otra
+// migración está corriendo de verdad, la interrumpe: usar solo después de
+// comprobar con LockStatus que el dueño ya no existe.
+func (m *Migrator) ReleaseLock(ctx context.Context) (LockInfo, error) {
+	inspector, err := m.lockInspector(ctx)
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	info, err := inspector.Inspect(ctx)
+	if err != nil {
+		return info, err
+	}
+	if !info.Held {
+		return info, nil
+	}
+
+	if err := inspector.ForceRelease(ctx); err != nil {
+		return info, fmt.Errorf("error liberando lock: %w", err)
+	}
+
+	m.log(ctx, slog.LevelWarn, "lock liberado a la fuerza",
+		slog.String("lock_key", info.Key),
+		slog.Int("pid", info.PID),
+		slog.String("host", info.Host))
+	return info, nil
+}
+
+func (m *Migrator) lockInspector(ctx context.Context) (LockInspector, error) {
+	key, err := m.lockKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error calculando la clave del lock: %w", err)
+	}
+
+	locker, err := m.newLocker(key)
+	if err != nil {
+		return nil, err
+	}
+
+	inspector, ok := locker.(LockInspector)
+	if !ok {
+		return nil, fmt.Errorf("el locker %T no permite inspeccionar el lock", locker)
+	}
+	return inspector, nil
+}
+
+// pgAdvisoryIDs separa la clave del advisory lock en las columnas classid y
+// objid con que aparece en pg_locks
+func pgAdvisoryIDs(key string) (classid, objid uint32) {
+	k := uint64(advisoryKey(key))
+	return uint32(k >> 32), uint32(k)
+}
+
+// Held comprueba en pg_locks que la sesión del lock siga teniéndolo. Si la
+// conexión se cortó, el advisory lock se liberó con ella.
+func (l *PostgresLocker) Held(ctx context.Context) (bool, error) {
+	if !l.locked {
+		return false, nil
+	}
+
+	classid, objid := pgAdvisoryIDs(l.key)
+	var held bool
+	err := l.conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND granted AND objsubid = 1 AND pid = pg_backend_pid()
+				AND classid::bigint = $1 AND objid::bigint = $2
+		)`, int64(classid), int64(objid)).Scan(&held)
+	return sessionHeld(ctx, held, err)
+}
+
+// sessionHeld interpreta la comprobación de un lock de sesión: un error que no
+// viene del contexto significa que la conexión, y con ella el lock, se perdió
+func sessionHeld(ctx context.Context, held bool, err error) (bool, error) {
+	if err != nil && ctx.Err() != nil {
+		return false, err
+	}
+	return err == nil && held, nil
+}
+
+func (l *PostgresLocker) Inspect(ctx context.Context) (LockInfo, error) {
+	info := LockInfo{Key: l.key}
+	classid, objid := pgAdvisoryIDs(l.key)
+
+	// objsubid = 1 identifica a los advisory locks de una sola clave bigint
+	var holder struct {
+		PID  int    `db:"pid"`
+		Host string `db:"host"`
+	}
+	err := l.db.GetContext(ctx, &holder, `
+		SELECT l.pid, COALESCE(a.client_hostname, host(a.client_addr), '') AS host
+		FROM pg_locks l LEFT JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted AND l.objsubid = 1
+			AND l.classid::bigint = $1 AND l.objid::bigint = $2
+		LIMIT 1`, int64(classid), int64(objid))
+	if errors.Is(err, sql.ErrNoRows) {
+		return info, nil
+	}
+	if err != nil {
+		return info, fmt.Errorf("error consultando pg_locks: %w", err)
+	}
+
+	info.Held = true
+	info.PID = holder.PID
+	info.Host = holder.Host
+	return info, nil
+}
+
+// ForceRelease termina la sesión que tiene el advisory lock: PostgreSQL solo
+// deja liberarlo desde esa sesión
+func (l *PostgresLocker) ForceRelease(ctx context.Context) error {
+	info, err := l.Inspect(ctx)
+	if err != nil || !info.Held {
+		return err
+	}
+
+	var terminated bool
+	if err := l.db.GetContext(ctx, &terminated, `SELECT pg_terminate_backend($1)`, info.PID); err != nil {
+		return err
+	}
+	if !terminated {
+		return fmt.Errorf("no se pudo terminar el backend %d", info.PID)
+	}
+	return nil
+}
+
+// Held comprueba que la conexión dedicada siga siendo la dueña del lock
+func (l *MySQLLocker) Held(ctx context.Context) (bool, error) {
+	if !l.locked {
+		return false, nil
+	}
+
+	var owner sql.NullBool
+	err := l.conn.QueryRowContext(ctx, `SELECT IS_USED_LOCK(?) = CONNECTION_ID()`, mysqlLockName(l.key)).Scan(&owner)
+	return sessionHeld(ctx, owner.Valid && owner.Bool, err)
+}
+
+func (l *MySQLLocker) Inspect(ctx context.Context) (LockInfo, error) {
+	info := LockInfo{Key: l.key}
+
+	var owner sql.NullInt64
+	if err := l.db.GetContext(ctx, &owner, `SELECT IS_USED_LOCK(?)`, mysqlLockName(l.key)); err != nil {
+		return info, fmt.Errorf("error consultando IS_USED_LOCK: %w", err)
+	}
+	if !owner.Valid {
+		return info, nil
+	}
+
+	info.Held = true
+	info.PID = int(owner.Int64)
+
+	// El host es informativo: sin permiso PROCESS no se ven otras conexiones
+	var host string
+	err := l.db.GetContext(ctx, &host, `SELECT HOST FROM information_schema.PROCESSLIST WHERE ID = ?`, owner.Int64)
+	if err == nil {
+		info.Host = host
+	}
+	return info, nil
+}
+
+// ForceRelease mata la conexión que tiene el lock: RELEASE_LOCK solo funciona
+// desde esa conexión
+func (l *MySQLLocker) ForceRelease(ctx context.Context) error {
+	info, err := l.Inspect(ctx)
+	if err != nil || !info.Held {
+		return err
+	}
+
+	_, err = l.db.ExecContext(ctx, fmt.Sprintf(`KILL %d`, info.PID))
+	return err
+}
+
+// Held comprueba que la fila del lock siga siendo la de este locker; no lo
+// es si alguien la liberó a la fuerza, aunque otro locker del mismo proceso
+// la haya vuelto a tomar
+func (l *SQLiteLocker) Held(ctx context.Context) (bool, error) {
+	if !l.locked {
+		return false, nil
+	}
+
+	var count int
+	err := l.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM migration_lock WHERE id = 1 AND owner = ?`, l.owner)
+	return count == 1, err
+}
+
+func (l *SQLiteLocker) Inspect(ctx context.Context) (LockInfo, error) {
+	info := LockInfo{Key: l.key}
+	if err := l.ensureTable(ctx); err != nil {
+		return info, err
+	}
+
+	var row struct {
+		LockedAt sql.NullTime   `db:"locked_at"`
+		PID      sql.NullInt64  `db:"pid"`
+		Host     sql.NullString `db:"host"`
+	}
+	err := l.db.GetContext(ctx, &row, `SELECT locked_at, pid, host FROM migration_lock WHERE id = 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return info, nil
+	}
+	if err != nil {
+		return info, fmt.Errorf("error consultando migration_lock: %w", err)
+	}
+
+	info.Held = true
+	info.PID = int(row.PID.Int64)
+	info.Host = row.Host.String
+	info.AcquiredAt = row.LockedAt.Time
+	return info, nil
+}
+
+// ForceRelease borra la fila del lock, que sobrevive al proceso que la creó
+func (l *SQLiteLocker) ForceRelease(ctx context.Context) error {
+	if err := l.ensureTable(ctx); err != nil {
+		return err
+	}
+	_, err := l.db.ExecContext(ctx, `DELETE FROM migration_lock WHERE id = 1`)
+	return err
+}
@@ -0,0 +1,212 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestLockStatus(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	m, _ := New(db, Options{})
+	ctx := context.Background()
+
+	t.Run("free lock", func(t *testing.T) {
+		info, err := m.LockStatus(ctx)
+		if err != nil {
+			t.Fatalf("LockStatus failed: %v", err)
+		}
+		if info.Held {
+			t.Errorf("lock should be free: %+v", info)
+		}
+		if info.Key != "schema_migrations" {
+			t.Errorf("unexpected key: %s", info.Key)
+		}
+	})
+
+	t.Run("held lock reports the holder", func(t *testing.T) {
+		holder, _ := NewLocker(db)
+		if err := holder.Lock(ctx); err != nil {
+			t.Fatalf("Lock failed: %v", err)
+		}
+		defer holder.Unlock()
+
+		info, err := m.LockStatus(ctx)
+		if err != nil {
+			t.Fatalf("LockStatus failed: %v", err)
+		}
+
+		host, _ := os.Hostname()
+		if !info.Held || info.PID != os.Getpid() || info.Host != host {
+			t.Errorf("unexpected lock info: %+v", info)
+		}
+		if info.AcquiredAt.IsZero() {
+			t.Error("acquisition time should be reported")
+		}
+	})
+}
+
+func TestReleaseLock(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	m, _ := New(db, Options{Source: os.DirFS(SetupTestMigrations(t))})
+	ctx := context.Background()
+
+	// Un lock que quedó de un proceso que murió sin liberarlo
+	stale, _ := NewLocker(db)
+	if err := stale.Lock(ctx); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	released, err := m.ReleaseLock(ctx)
+	if err != nil {
+		t.Fatalf("ReleaseLock failed: %v", err)
+	}
+	if !released.Held {
+		t.Error("released info should describe the held lock")
+	}
+
+	info, _ := m.LockStatus(ctx)
+	if info.Held {
+		t.Error("lock should be free after release")
+	}
+
+	upCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := m.Up(upCtx); err != nil {
+		t.Errorf("Up should acquire the released lock: %v", err)
+	}
+
+	t.Run("releasing a free lock is a no-op", func(t *testing.T) {
+		info, err := m.ReleaseLock(ctx)
+		if err != nil || info.Held {
+			t.Errorf("unexpected result: %+v, %v", info, err)
+		}
+	})
+}
+
+func TestSQLiteLockTableUpgrade(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	// Tabla de lock creada por una versión anterior
+	db.MustExec(`CREATE TABLE migration_lock (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		locked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	db.MustExec(`INSERT INTO migration_lock (id) VALUES (1)`)
+
+	m, _ := New(db, Options{})
+	info, err := m.LockStatus(context.Background())
+	if err != nil {
+		t.Fatalf("LockStatus failed: %v", err)
+	}
+	if !info.Held || info.PID != 0 || info.Host != "" {
+		t.Errorf("unexpected lock info: %+v", info)
+	}
+}
+
+func TestLockLostCancelsMigration(t *testing.T) {
+	// Base en archivo con varias conexiones: la comprobación del lock corre
+	// mientras la migración tiene su propia transacción abierta
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "lock.db"))
+	if err != nil {
+		t.Fatalf("error opening db: %v", err)
+	}
+	defer db.Close()
+
+	started := make(chan struct{})
+	registry := NewRegistry()
+	registry.Register(1, "long_backfill", func(ctx context.Context, tx *sqlx.Tx) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}, nil)
+
+	m, _ := New(db, Options{Registry: registry, LockCheckInterval: 20 * time.Millisecond})
+
+	go func() {
+		<-started
+		// Alguien libera el lock a la fuerza mientras la migración corre
+		db.MustExec(`DELETE FROM migration_lock`)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = m.Up(ctx)
+	if !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("migration should be cancelled by the lock loss, not the deadline")
+	}
+
+	var versions []int
+	db.Select(&versions, `SELECT version FROM schema_migrations`)
+	if len(versions) != 0 {
+		t.Errorf("cancelled migration should not be recorded: %v", versions)
+	}
+}
+
+func TestLockHeld(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	lockers := map[string]func() Locker{
+		"sqlite": func() Locker { return NewSQLiteLocker(db, "k") },
+		"table":  func() Locker { return NewTableLocker(db, "k") },
+	}
+
+	for name, newLocker := range lockers {
+		t.Run(name, func(t *testing.T) {
+			locker := newLocker()
+			monitor := locker.(LockMonitor)
+			if held, _ := monitor.Held(ctx); held {
+				t.Error("lock should not be held before Lock")
+			}
+
+			if err := locker.Lock(ctx); err != nil {
+				t.Fatalf("Lock failed: %v", err)
+			}
+			defer locker.Unlock()
+
+			if held, err := monitor.Held(ctx); err != nil || !held {
+				t.Errorf("lock should be held: %v", err)
+			}
+
+			if err := locker.(LockInspector).ForceRelease(ctx); err != nil {
+				t.Fatalf("ForceRelease failed: %v", err)
+			}
+			if held, err := monitor.Held(ctx); err != nil || held {
+				t.Errorf("lock should be lost after ForceRelease: %v", err)
+			}
+
+			// Otro locker del mismo proceso toma el lock liberado
+			other := newLocker()
+			if err := other.Lock(ctx); err != nil {
+				t.Fatalf("Lock after ForceRelease failed: %v", err)
+			}
+			defer other.Unlock()
+
+			if held, err := monitor.Held(ctx); err != nil || held {
+				t.Errorf("lock should stay lost after another locker takes it: %v", err)
+			}
+			if err := locker.Unlock(); err != nil {
+				t.Fatalf("Unlock failed: %v", err)
+			}
+			if held, err := other.(LockMonitor).Held(ctx); err != nil || !held {
+				t.Errorf("the previous holder must not release the new lock: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Logger recibe los eventos de la librería. Cada evento de una migración
+// lleva los atributos version, name, direction y dry_run, y al terminar
+// también duration.
+type Logger interface {
+	Log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr)
+}
+
+// SlogLogger adapta un *slog.Logger a Logger
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	s.l.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// NopLogger descarta todos los eventos. Es el Logger por defecto de un Migrator.
+type NopLogger struct{}
+
+func (NopLogger) Log(context.Context, slog.Level, string, ...slog.Attr) {}
+
+func (m *Migrator) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	m.logger.Log(ctx, level, msg, attrs...)
+}
+
+// migrationAttrs son los atributos comunes a todos los eventos de una migración
+func (m *Migrator) migrationAttrs(mig Migration, dir Direction, extra ...slog.Attr) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Int("version", mig.Version),
+		slog.String("name", mig.Name),
+		slog.String("direction", string(dir)),
+		slog.Bool("dry_run", m.opts.DryRun),
+	}
+	return append(attrs, extra...)
+}
+
+func durationAttr(d time.Duration) slog.Attr {
+	return slog.Duration("duration", d)
+}
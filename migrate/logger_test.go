@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+// logRecords decodifica la salida de un slog.JSONHandler, un registro por línea
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var r map[string]any
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("error decodificando log: %v", err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestSlogLogger(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	m, _ := New(db, Options{
+		Source: os.DirFS(SetupTestMigrations(t)),
+		Logger: SlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+	})
+
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	var completed []map[string]any
+	for _, r := range logRecords(t, &buf) {
+		if r["msg"] == "migración completada" {
+			completed = append(completed, r)
+		}
+	}
+
+	if len(completed) != 3 {
+		t.Fatalf("expected 3 completed events, got %d", len(completed))
+	}
+	for _, key := range []string{"version", "name", "direction", "duration", "dry_run"} {
+		if _, ok := completed[0][key]; !ok {
+			t.Errorf("event missing field %q: %v", key, completed[0])
+		}
+	}
+	if completed[0]["direction"] != "up" || completed[0]["dry_run"] != false {
+		t.Errorf("unexpected event fields: %v", completed[0])
+	}
+}
+
+func TestSlogLoggerDryRun(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	m, _ := New(db, Options{
+		Source: os.DirFS(SetupTestMigrations(t)),
+		Logger: SlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		DryRun: true,
+	})
+
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up dry-run failed: %v", err)
+	}
+
+	planned := 0
+	for _, r := range logRecords(t, &buf) {
+		if r["msg"] == "migración planificada" {
+			planned++
+			if r["dry_run"] != true || r["sql"] == "" {
+				t.Errorf("unexpected dry-run event: %v", r)
+			}
+		}
+	}
+	if planned != 3 {
+		t.Errorf("expected 3 planned events, got %d", planned)
+	}
+}
+
+func TestDefaultLoggerIsNop(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	m, _ := New(db, Options{})
+	if _, ok := m.logger.(NopLogger); !ok {
+		t.Errorf("expected NopLogger by default, got %T", m.logger)
+	}
+}
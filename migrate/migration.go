@@ -1,8 +1,79 @@
 package migrate
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
 type Migration struct {
 	Version int
 	Name    string
 	UpSQL   string
 	DownSQL string
-}
\ No newline at end of file
+	// UpNoTransaction y DownNoTransaction vienen de la directiva
+	// "-- migrate:no-transaction" al principio del archivo: el script se
+	// ejecuta fuera de una transacción (CREATE INDEX CONCURRENTLY, VACUUM, ...)
+	UpNoTransaction   bool
+	DownNoTransaction bool
+	// UpFunc y DownFunc están definidas en las migraciones escritas en Go
+	// (ver Register) y reemplazan a UpSQL y DownSQL
+	UpFunc   MigrationFunc
+	DownFunc MigrationFunc
+}
+
+// fn devuelve la función Go a ejecutar en la dirección indicada, o nil si la
+// migración es SQL
+func (m Migration) fn(dir Direction) MigrationFunc {
+	if dir == DirectionDown {
+		return m.DownFunc
+	}
+	return m.UpFunc
+}
+
+// noTransaction indica si el script de la dirección indicada debe
+// ejecutarse fuera de una transacción. Las migraciones en Go siempre usan una.
+func (m Migration) noTransaction(dir Direction) bool {
+	if m.fn(dir) != nil {
+		return false
+	}
+	if dir == DirectionDown {
+		return m.DownNoTransaction
+	}
+	return m.UpNoTransaction
+}
+
+// hasDown indica si la migración se puede revertir
+func (m Migration) hasDown() bool {
+	return m.DownSQL != "" || m.DownFunc != nil
+}
+
+// script devuelve el SQL a ejecutar en la dirección indicada
+func (m Migration) script(dir Direction) string {
+	if dir == DirectionDown {
+		return m.DownSQL
+	}
+	return m.UpSQL
+}
+
+// Checksum devuelve el SHA-256 en hexadecimal del UpSQL normalizado. La
+// normalización ignora finales de línea CRLF, espacios al final de cada línea
+// y líneas vacías al principio y al final, para que cambios de editor no
+// cuenten como modificaciones. Las migraciones en Go no tienen checksum.
+func (m Migration) Checksum() string {
+	if m.UpFunc != nil {
+		return ""
+	}
+	return sqlChecksum(m.UpSQL)
+}
+
+func sqlChecksum(sql string) string {
+	lines := strings.Split(strings.ReplaceAll(sql, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	normalized := strings.Trim(strings.Join(lines, "\n"), "\n")
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
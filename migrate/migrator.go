@@ -0,0 +1,386 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// DefaultTableName es la tabla de control usada si Options.TableName está vacío
+	DefaultTableName = "schema_migrations"
+	// DefaultLockTimeout es el tiempo de espera del lock si Options.LockTimeout es 0
+	DefaultLockTimeout = 30 * time.Second
+	// DefaultLockCheckInterval es el valor de Options.LockCheckInterval si es 0
+	DefaultLockCheckInterval = 10 * time.Second
+)
+
+// Options configura un Migrator
+type Options struct {
+	// Source es de donde se leen las migraciones, por ejemplo os.DirFS o un
+	// embed.FS. Es obligatorio para todo lo que no sea Status.
+	Source fs.FS
+	// Seeds es de donde Seed lee los archivos de datos
+	Seeds fs.FS
+	// Load configura la validación de los archivos de migración
+	Load LoadOptions
+	// Registry aporta las migraciones escritas en Go. Por defecto se usa el
+	// registro global de Register.
+	Registry *Registry
+	// TableName es la tabla de control de versiones
+	TableName string
+	// Schema califica la tabla de control: el schema en PostgreSQL, la base de
+	// datos en MySQL o la base adjunta en SQLite. Vacío usa el de la conexión.
+	Schema string
+	// LockTimeout es el tiempo máximo para adquirir el lock
+	LockTimeout time.Duration
+	// LockCheckInterval es cada cuánto se comprueba que el lock siga tomado
+	// mientras se migra
+	LockCheckInterval time.Duration
+	// Lock elige el mecanismo de lock. Por defecto LockNative.
+	Lock LockStrategy
+	// LockKey identifica el lock de migraciones. Por defecto se deriva del
+	// nombre de la base de datos y de la tabla de control, así los Migrator
+	// de otras bases o con otra tabla no se bloquean entre sí.
+	LockKey string
+	// Logger recibe los eventos de progreso. Por defecto NopLogger.
+	Logger Logger
+	// Hooks se llaman alrededor de cada migración y de cada ejecución, además
+	// de los callbacks SQL de Source/_callbacks
+	Hooks Hooks
+	// DryRun muestra lo que se haría sin modificar la base de datos
+	DryRun bool
+	// VerifyChecksums hace que Up, Down y Goto fallen si el archivo de alguna
+	// migración aplicada cambió desde que se aplicó
+	VerifyChecksums bool
+	// AllowOutOfOrder deja que Up y Goto apliquen migraciones pendientes con
+	// versión menor a la última aplicada. Por defecto fallan con
+	// *OutOfOrderError.
+	AllowOutOfOrder bool
+	// AllowDestructive habilita Reset y Fresh, que borran datos. Activalo
+	// solo para bases locales o de tests; sin él fallan con ErrDestructive.
+	AllowDestructive bool
+}
+
+// LockStrategy indica cómo se excluyen las migraciones concurrentes
+type LockStrategy string
+
+const (
+	// LockNative usa el mecanismo propio del driver (advisory locks, GET_LOCK,
+	// la tabla migration_lock de SQLite) y TableLocker para los demás drivers
+	LockNative LockStrategy = "native"
+	// LockTable usa TableLocker con cualquier driver
+	LockTable LockStrategy = "table"
+)
+
+// Direction indica si una migración se aplica o se revierte
+type Direction string
+
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+)
+
+// Step describe una migración ejecutada (o que se ejecutaría en dry-run)
+type Step struct {
+	Version   int
+	Name      string
+	Direction Direction
+	Duration  time.Duration
+	// SQL es el script ejecutado, o el que se ejecutaría en dry-run
+	SQL string
+	// OutOfOrder indica que la migración se aplicó con versión menor a la
+	// última aplicada (ver Options.AllowOutOfOrder)
+	OutOfOrder bool
+	// Repeatable indica una migración repetible; Version es 0
+	Repeatable bool
+}
+
+// Result es lo que devuelven las operaciones del Migrator. Si la operación
+// falla a mitad de camino, Steps contiene las migraciones que sí terminaron.
+type Result struct {
+	DryRun bool
+	Steps  []Step
+	// Skipped son los seeds que Seed no ejecutó por ser de otro entorno
+	Skipped []string
+	// Dropped son los objetos que Fresh eliminó antes de migrar
+	Dropped []string
+}
+
+// Migrator ejecuta migraciones sobre una base de datos con una configuración fija
+type Migrator struct {
+	db      *sqlx.DB
+	opts    Options
+	logger  Logger
+	dialect Dialect
+	// table es el nombre calificado y entre comillas de la tabla de control
+	table string
+	// repeatables es la tabla de control de las migraciones repetibles
+	repeatables string
+	// seeds es la tabla de control de los seeds
+	seeds string
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// New crea un Migrator aplicando los valores por defecto de Options
+func New(db *sqlx.DB, opts Options) (*Migrator, error) {
+	if db == nil {
+		return nil, errors.New("la conexión a la base de datos es obligatoria")
+	}
+	if opts.TableName == "" {
+		opts.TableName = DefaultTableName
+	}
+	if !identifier.MatchString(opts.TableName) {
+		return nil, fmt.Errorf("nombre de tabla inválido: %q", opts.TableName)
+	}
+	if opts.Schema != "" && !identifier.MatchString(opts.Schema) {
+		return nil, fmt.Errorf("nombre de schema inválido: %q", opts.Schema)
+	}
+	switch opts.Lock {
+	case "":
+		opts.Lock = LockNative
+	case LockNative, LockTable:
+	default:
+		return nil, fmt.Errorf("estrategia de lock desconocida: %q", opts.Lock)
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = DefaultLockTimeout
+	}
+	if opts.LockCheckInterval <= 0 {
+		opts.LockCheckInterval = DefaultLockCheckInterval
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = NopLogger{}
+	}
+
+	dialect := DialectFor(db.DriverName())
+	return &Migrator{
+		db:      db,
+		opts:    opts,
+		logger:  logger,
+		dialect: dialect,
+		table:   qualifiedName(dialect, opts.Schema, opts.TableName),
+		// Las repetibles no tienen versión: se registran por nombre en una
+		// tabla hermana de la de control
+		repeatables: qualifiedName(dialect, opts.Schema, opts.TableName+"_repeatable"),
+		seeds:       qualifiedName(dialect, opts.Schema, opts.TableName+"_seeds"),
+	}, nil
+}
+
+// quoteIdent pone un identificador entre las comillas del dialecto,
+// duplicando las que tenga adentro
+func quoteIdent(dialect Dialect, name string) string {
+	if dialect == DialectMySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// qualifiedName arma schema.nombre con cada parte entre comillas
+func qualifiedName(dialect Dialect, schema, name string) string {
+	if schema == "" {
+		return quoteIdent(dialect, name)
+	}
+	return quoteIdent(dialect, schema) + "." + quoteIdent(dialect, name)
+}
+
+// load lee las migraciones de Options.Source y las combina con las
+// registradas en Go
+func (m *Migrator) load() ([]Migration, error) {
+	registered := m.registry().list()
+	if m.opts.Source == nil {
+		if len(registered) == 0 {
+			return nil, errors.New("Options.Source es obligatorio para cargar migraciones")
+		}
+		return registered, nil
+	}
+
+	files, err := LoadFSWithOptions(m.opts.Source, m.opts.Load)
+	if err != nil {
+		return nil, err
+	}
+	return merge(files, registered)
+}
+
+func (m *Migrator) registry() *Registry {
+	if m.opts.Registry != nil {
+		return m.opts.Registry
+	}
+	return defaultRegistry
+}
+
+// lock adquiere el lock de migraciones respetando Options.LockTimeout. Devuelve
+// un contexto para trabajar con el lock tomado, que se cancela con causa
+// ErrLockLost si el lock se pierde, y la función que lo libera.
+func (m *Migrator) lock(ctx context.Context) (context.Context, func(), error) {
+	waitCtx, cancel := context.WithTimeout(ctx, m.opts.LockTimeout)
+	defer cancel()
+
+	key, err := m.lockKey(waitCtx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error calculando la clave del lock: %w", err)
+	}
+
+	locker, err := m.newLocker(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creando locker: %w", err)
+	}
+
+	stop := m.logLockWait(waitCtx, key)
+	err = locker.Lock(waitCtx)
+	stop()
+	if err != nil {
+		return nil, nil, fmt.Errorf("no se pudo adquirir lock: %w", err)
+	}
+
+	lockCtx, cancelLock := context.WithCancelCause(ctx)
+	stopMonitor := m.monitorLock(lockCtx, locker, key, cancelLock)
+	return lockCtx, func() {
+		stopMonitor()
+		cancelLock(nil)
+		locker.Unlock()
+	}, nil
+}
+
+// newLocker crea el locker de Options.Lock
+func (m *Migrator) newLocker(key string) (Locker, error) {
+	if m.opts.Lock == LockTable {
+		return NewTableLocker(m.db, key), nil
+	}
+	return NewLockerWithKey(m.db, key)
+}
+
+// lockWaitLogInterval es cada cuánto se registra que se sigue esperando el lock
+var lockWaitLogInterval = 5 * time.Second
+
+// logLockWait registra periódicamente la espera del lock hasta que se llama a
+// la función devuelta
+func (m *Migrator) logLockWait(ctx context.Context, key string) (stop func()) {
+	start := time.Now()
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(lockWaitLogInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.log(ctx, slog.LevelInfo, "esperando el lock de migraciones",
+					slog.String("lock_key", key),
+					durationAttr(time.Since(start)),
+					slog.Duration("timeout", m.opts.LockTimeout))
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// monitorLock comprueba cada Options.LockCheckInterval que el lock siga tomado
+// y, si se perdió, cancela el contexto con ErrLockLost. Los lockers que no
+// implementan LockMonitor no se comprueban.
+func (m *Migrator) monitorLock(ctx context.Context, locker Locker, key string, cancel context.CancelCauseFunc) (stop func()) {
+	monitor, ok := locker.(LockMonitor)
+	if !ok {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(m.opts.LockCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			checkCtx, cancelCheck := context.WithTimeout(ctx, m.opts.LockCheckInterval)
+			held, err := monitor.Held(checkCtx)
+			cancelCheck()
+
+			switch {
+			case err != nil:
+				// No se sabe si el lock sigue tomado: se vuelve a comprobar en el próximo tick
+				m.log(ctx, slog.LevelWarn, "no se pudo comprobar el lock de migraciones",
+					slog.String("lock_key", key), slog.String("error", err.Error()))
+			case !held:
+				m.log(ctx, slog.LevelError, "se perdió el lock de migraciones", slog.String("lock_key", key))
+				cancel(ErrLockLost)
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// lockKey devuelve Options.LockKey o, si está vacío, base/schema.tabla
+func (m *Migrator) lockKey(ctx context.Context) (string, error) {
+	if m.opts.LockKey != "" {
+		return m.opts.LockKey, nil
+	}
+
+	database, err := m.databaseName(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	table := m.opts.TableName
+	if m.opts.Schema != "" {
+		table = m.opts.Schema + "." + table
+	}
+	if database == "" {
+		return table, nil
+	}
+	return database + "/" + table, nil
+}
+
+// databaseName devuelve la base de datos de la conexión. En SQLite la base es
+// el archivo y no tiene nombre.
+func (m *Migrator) databaseName(ctx context.Context) (string, error) {
+	var query string
+	switch m.dialect {
+	case DialectPostgres:
+		query = `SELECT current_database()`
+	case DialectMySQL:
+		query = `SELECT DATABASE()`
+	default:
+		return "", nil
+	}
+
+	var name sql.NullString
+	if err := m.db.GetContext(ctx, &name, query); err != nil {
+		return "", err
+	}
+	return name.String, nil
+}
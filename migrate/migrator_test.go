@@ -0,0 +1,187 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	t.Run("defaults", func(t *testing.T) {
+		m, err := New(db, Options{})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+
+		if m.opts.TableName != DefaultTableName {
+			t.Errorf("expected table %s, got %s", DefaultTableName, m.opts.TableName)
+		}
+		if m.opts.LockTimeout != DefaultLockTimeout {
+			t.Errorf("expected lock timeout %s, got %s", DefaultLockTimeout, m.opts.LockTimeout)
+		}
+	})
+
+	t.Run("invalid table name", func(t *testing.T) {
+		_, err := New(db, Options{TableName: "users; DROP TABLE users"})
+		if err == nil {
+			t.Error("expected error with invalid table name")
+		}
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		_, err := New(db, Options{Schema: "public.x"})
+		if err == nil {
+			t.Error("expected error with invalid schema")
+		}
+	})
+
+	t.Run("nil db", func(t *testing.T) {
+		if _, err := New(nil, Options{}); err == nil {
+			t.Error("expected error with nil db")
+		}
+	})
+
+	t.Run("up without source", func(t *testing.T) {
+		m, _ := New(db, Options{})
+		if _, err := m.Up(context.Background()); err == nil {
+			t.Error("expected error without Source")
+		}
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	m, err := New(db, Options{
+		Source:    os.DirFS(SetupTestMigrations(t)),
+		TableName: "custom_migrations",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("up returns applied steps", func(t *testing.T) {
+		result, err := m.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+
+		if len(result.Steps) != 3 {
+			t.Fatalf("expected 3 steps, got %d", len(result.Steps))
+		}
+		for i, step := range result.Steps {
+			if step.Version != i+1 || step.Direction != DirectionUp {
+				t.Errorf("unexpected step %d: %+v", i, step)
+			}
+		}
+
+		if !TableExists(t, db, "custom_migrations") {
+			t.Error("custom state table was not created")
+		}
+		if TableExists(t, db, DefaultTableName) {
+			t.Error("default state table should not be created")
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		versions, err := m.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status failed: %v", err)
+		}
+		if len(versions) != 3 {
+			t.Errorf("expected 3 versions, got %v", versions)
+		}
+	})
+
+	t.Run("down returns reverted steps", func(t *testing.T) {
+		result, err := m.Down(ctx, 2)
+		if err != nil {
+			t.Fatalf("Down failed: %v", err)
+		}
+
+		if len(result.Steps) != 2 || result.Steps[0].Version != 3 || result.Steps[1].Version != 2 {
+			t.Errorf("unexpected steps: %+v", result.Steps)
+		}
+
+		versions, _ := m.Status(ctx)
+		if len(versions) != 1 || versions[0] != 1 {
+			t.Errorf("expected [1], got %v", versions)
+		}
+	})
+}
+
+func TestMigratorSchema(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`ATTACH DATABASE ':memory:' AS other`); err != nil {
+		t.Fatalf("error attaching database: %v", err)
+	}
+
+	// "order" es una palabra reservada: solo funciona si el nombre va entre comillas
+	m, err := New(db, Options{
+		Source:    os.DirFS(SetupTestMigrations(t)),
+		TableName: "order",
+		Schema:    "other",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM other.sqlite_master WHERE type = 'table' AND name = 'order'`); err != nil {
+		t.Fatalf("error querying schema: %v", err)
+	}
+	if count != 1 {
+		t.Error("state table was not created in the schema")
+	}
+	if TableExists(t, db, "order") {
+		t.Error("state table should not be created in the main schema")
+	}
+}
+
+func TestQualifiedName(t *testing.T) {
+	cases := []struct {
+		dialect  Dialect
+		schema   string
+		expected string
+	}{
+		{DialectPostgres, "", `"schema_migrations"`},
+		{DialectPostgres, "billing", `"billing"."schema_migrations"`},
+		{DialectMySQL, "billing", "`billing`.`schema_migrations`"},
+		{DialectSQLite, "main", `"main"."schema_migrations"`},
+	}
+
+	for _, tc := range cases {
+		if got := qualifiedName(tc.dialect, tc.schema, DefaultTableName); got != tc.expected {
+			t.Errorf("%s/%q: expected %s, got %s", tc.dialect, tc.schema, tc.expected, got)
+		}
+	}
+}
+
+func TestMigratorCancelledContext(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	m, _ := New(db, Options{
+		Source: os.DirFS(SetupTestMigrations(t)),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := m.Up(ctx); err == nil {
+		t.Fatal("expected error with cancelled context")
+	}
+
+	AssertMigrationsApplied(t, db, []int{})
+}
@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OutOfOrderError lo devuelven Up y Goto cuando hay migraciones pendientes
+// con versión menor a la última aplicada, por ejemplo de una rama que se
+// mergeó tarde. Options.AllowOutOfOrder las aplica igual.
+type OutOfOrderError struct {
+	// Latest es la última versión aplicada
+	Latest int
+	// Versions son las versiones pendientes anteriores a Latest, en orden
+	Versions []int
+}
+
+func (e *OutOfOrderError) Error() string {
+	versions := make([]string, len(e.Versions))
+	for i, v := range e.Versions {
+		versions[i] = strconv.Itoa(v)
+	}
+	return fmt.Sprintf("%d migración(es) pendiente(s) anteriores a la última aplicada (%d): %s\n"+
+		"si es intencional aplicalas con --allow-out-of-order (Options.AllowOutOfOrder)",
+		len(e.Versions), e.Latest, strings.Join(versions, ", "))
+}
+
+// checkOrder falla si el plan aplica migraciones con versión menor a latest,
+// salvo con Options.AllowOutOfOrder
+func (m *Migrator) checkOrder(plan []Migration, dir Direction, latest int) error {
+	if dir != DirectionUp || m.opts.AllowOutOfOrder {
+		return nil
+	}
+
+	var late []int
+	for _, mig := range plan {
+		if mig.Version < latest {
+			late = append(late, mig.Version)
+		}
+	}
+	if len(late) > 0 {
+		return &OutOfOrderError{Latest: latest, Versions: late}
+	}
+	return nil
+}
+
+// latestVersion devuelve la última de las versiones aplicadas, que vienen en
+// orden ascendente
+func latestVersion(appliedVersions []int) int {
+	if len(appliedVersions) == 0 {
+		return 0
+	}
+	return appliedVersions[len(appliedVersions)-1]
+}
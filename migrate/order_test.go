@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOutOfOrder(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	first, _ := New(db, Options{Source: fstest.MapFS{
+		"1_users.up.sql": {Data: []byte("CREATE TABLE users (id INTEGER);")},
+		"4_posts.up.sql": {Data: []byte("CREATE TABLE posts (id INTEGER);")},
+	}})
+	if _, err := first.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	// Se mergeó una rama con la 2 y la 3, anteriores a la 4 ya aplicada
+	source := fstest.MapFS{
+		"1_users.up.sql":    {Data: []byte("CREATE TABLE users (id INTEGER);")},
+		"2_emails.up.sql":   {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"3_tags.up.sql":     {Data: []byte("CREATE TABLE tags (id INTEGER);")},
+		"4_posts.up.sql":    {Data: []byte("CREATE TABLE posts (id INTEGER);")},
+		"5_comments.up.sql": {Data: []byte("CREATE TABLE comments (id INTEGER);")},
+	}
+
+	t.Run("fails by default", func(t *testing.T) {
+		for _, dryRun := range []bool{false, true} {
+			m, _ := New(db, Options{Source: source, DryRun: dryRun})
+			result, err := m.Up(ctx)
+
+			var orderErr *OutOfOrderError
+			if !errors.As(err, &orderErr) {
+				t.Fatalf("dry-run %v: expected OutOfOrderError, got %v", dryRun, err)
+			}
+			if orderErr.Latest != 4 || len(orderErr.Versions) != 2 || orderErr.Versions[0] != 2 || orderErr.Versions[1] != 3 {
+				t.Errorf("unexpected error contents: %+v", orderErr)
+			}
+			if len(result.Steps) != 0 {
+				t.Errorf("expected nothing to run, got %+v", result.Steps)
+			}
+		}
+		AssertMigrationsApplied(t, db, []int{1, 4})
+	})
+
+	t.Run("goto fails too", func(t *testing.T) {
+		m, _ := New(db, Options{Source: source})
+		var orderErr *OutOfOrderError
+		if _, err := m.Goto(ctx, 5); !errors.As(err, &orderErr) {
+			t.Fatalf("expected OutOfOrderError, got %v", err)
+		}
+	})
+
+	t.Run("AllowOutOfOrder applies and records them", func(t *testing.T) {
+		m, _ := New(db, Options{Source: source, AllowOutOfOrder: true})
+		result, err := m.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+
+		outOfOrder := map[int]bool{}
+		for _, step := range result.Steps {
+			outOfOrder[step.Version] = step.OutOfOrder
+		}
+		if !outOfOrder[2] || !outOfOrder[3] || outOfOrder[5] {
+			t.Errorf("unexpected OutOfOrder in steps: %+v", result.Steps)
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2, 3, 4, 5})
+
+		report, err := m.StatusReport(ctx)
+		if err != nil {
+			t.Fatalf("StatusReport failed: %v", err)
+		}
+		for _, s := range report.Migrations {
+			want := s.Version == 2 || s.Version == 3
+			if s.OutOfOrder != want {
+				t.Errorf("version %d: expected OutOfOrder %v, got %v", s.Version, want, s.OutOfOrder)
+			}
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MigrationFunc es el cuerpo de una migración escrita en Go. Corre dentro de
+// la misma transacción que registra la migración en la tabla de control.
+type MigrationFunc func(ctx context.Context, tx *sqlx.Tx) error
+
+// Registry guarda migraciones escritas en Go. Un Migrator las combina con
+// las migraciones SQL de Options.Source en una sola lista ordenada.
+type Registry struct {
+	mu         sync.Mutex
+	migrations map[int]Migration
+}
+
+// NewRegistry crea un registro vacío
+func NewRegistry() *Registry {
+	return &Registry{migrations: map[int]Migration{}}
+}
+
+// defaultRegistry es el que usa Register y el que usa un Migrator cuando
+// Options.Registry es nil
+var defaultRegistry = NewRegistry()
+
+// Register agrega una migración en Go al registro global. Está pensada para
+// llamarse desde un init(); entra en pánico si la versión es inválida o está
+// repetida. down puede ser nil si la migración no se puede revertir.
+func Register(version int, name string, up, down MigrationFunc) {
+	defaultRegistry.Register(version, name, up, down)
+}
+
+// Register agrega una migración en Go al registro
+func (r *Registry) Register(version int, name string, up, down MigrationFunc) {
+	if version < 1 {
+		panic(fmt.Sprintf("migrate: la versión de %q debe ser mayor a 0", name))
+	}
+	if name == "" || up == nil {
+		panic(fmt.Sprintf("migrate: la migración %d necesita nombre y función up", version))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.migrations == nil {
+		r.migrations = map[int]Migration{}
+	}
+	if prev, ok := r.migrations[version]; ok {
+		panic(fmt.Sprintf("migrate: la versión %d ya está registrada por %q", version, prev.Name))
+	}
+	r.migrations[version] = Migration{Version: version, Name: name, UpFunc: up, DownFunc: down}
+}
+
+// list devuelve las migraciones registradas en orden de versión
+func (r *Registry) list() []Migration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Migration, 0, len(r.migrations))
+	for _, mig := range r.migrations {
+		out = append(out, mig)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Version < out[j].Version
+	})
+	return out
+}
+
+// merge combina las migraciones SQL con las registradas en Go. Una misma
+// versión no puede estar definida de las dos formas.
+func merge(files, registered []Migration) ([]Migration, error) {
+	byVersion := make(map[int]Migration, len(files))
+	for _, mig := range files {
+		byVersion[mig.Version] = mig
+	}
+
+	out := append([]Migration(nil), files...)
+	for _, mig := range registered {
+		if prev, ok := byVersion[mig.Version]; ok {
+			return nil, fmt.Errorf("la versión %d está definida como archivo SQL (%s) y como migración Go (%s)", mig.Version, prev.Name, mig.Name)
+		}
+		out = append(out, mig)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Version < out[j].Version
+	})
+	return out, nil
+}
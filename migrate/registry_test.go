@@ -0,0 +1,143 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestGoMigrations(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	registry := NewRegistry()
+	registry.Register(4, "backfill_emails", func(ctx context.Context, tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO users (id, email, name) VALUES (1, 'a@example.com', 'A')`)
+		return err
+	}, func(ctx context.Context, tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = 1`)
+		return err
+	})
+
+	m, _ := New(db, Options{Source: os.DirFS(SetupTestMigrations(t)), Registry: registry})
+	ctx := context.Background()
+
+	t.Run("go migrations merge with sql files", func(t *testing.T) {
+		result, err := m.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1, 2, 3, 4})
+		if result.Steps[3].Name != "backfill_emails" {
+			t.Errorf("expected go migration last, got %+v", result.Steps[3])
+		}
+
+		var count int
+		db.Get(&count, `SELECT COUNT(*) FROM users`)
+		if count != 1 {
+			t.Errorf("expected 1 user, got %d", count)
+		}
+	})
+
+	t.Run("go migrations can be reverted", func(t *testing.T) {
+		if _, err := m.Down(ctx, 1); err != nil {
+			t.Fatalf("Down failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1, 2, 3})
+
+		var count int
+		db.Get(&count, `SELECT COUNT(*) FROM users`)
+		if count != 0 {
+			t.Errorf("expected 0 users, got %d", count)
+		}
+	})
+}
+
+func TestGoMigrationRollback(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	registry := NewRegistry()
+	registry.Register(1, "fails_halfway", func(ctx context.Context, tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE partial (id INTEGER)`); err != nil {
+			return err
+		}
+		return errors.New("backfill failed")
+	}, nil)
+
+	m, _ := New(db, Options{Registry: registry})
+
+	if _, err := m.Up(context.Background()); err == nil {
+		t.Fatal("expected error from go migration")
+	}
+
+	if TableExists(t, db, "partial") {
+		t.Error("go migration was not rolled back")
+	}
+	AssertMigrationsApplied(t, db, []int{})
+}
+
+func TestGoMigrationWithoutDown(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	registry := NewRegistry()
+	registry.Register(1, "irreversible", func(ctx context.Context, tx *sqlx.Tx) error {
+		return nil
+	}, nil)
+
+	m, _ := New(db, Options{Registry: registry})
+	ctx := context.Background()
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if _, err := m.Down(ctx, 1); err == nil {
+		t.Error("expected error reverting migration without down")
+	}
+}
+
+func TestGoMigrationVersionConflict(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	registry := NewRegistry()
+	registry.Register(2, "duplicated", func(ctx context.Context, tx *sqlx.Tx) error {
+		return nil
+	}, nil)
+
+	m, _ := New(db, Options{Source: os.DirFS(SetupTestMigrations(t)), Registry: registry})
+
+	if _, err := m.Up(context.Background()); err == nil {
+		t.Error("expected error when sql file and go migration share a version")
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	noop := func(ctx context.Context, tx *sqlx.Tx) error { return nil }
+
+	cases := map[string]func(r *Registry){
+		"duplicate version": func(r *Registry) {
+			r.Register(1, "first", noop, nil)
+			r.Register(1, "second", noop, nil)
+		},
+		"invalid version": func(r *Registry) { r.Register(0, "zero", noop, nil) },
+		"missing up":      func(r *Registry) { r.Register(1, "no_up", nil, nil) },
+	}
+
+	for name, register := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			register(NewRegistry())
+		})
+	}
+}
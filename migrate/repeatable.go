@@ -0,0 +1,270 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Repeatable es una migración repetible: un archivo R_<nombre>.sql con
+// vistas, funciones o triggers que Up vuelve a ejecutar cada vez que su
+// contenido cambia. No tiene versión.
+type Repeatable struct {
+	Name string
+	SQL  string
+	// NoTransaction viene de la directiva "-- migrate:no-transaction"
+	NoTransaction bool
+	// DownSQL es el script opcional R_<nombre>.down.sql que elimina sus
+	// objetos. Reset lo ejecuta antes de revertir las migraciones, que
+	// pueden fallar si una vista o un trigger depende de sus tablas.
+	DownSQL           string
+	DownNoTransaction bool
+}
+
+// Checksum devuelve el SHA-256 del SQL normalizado, igual que
+// Migration.Checksum
+func (r Repeatable) Checksum() string {
+	return sqlChecksum(r.SQL)
+}
+
+// RepeatableStatus es el estado de una migración repetible en un StatusReport
+type RepeatableStatus struct {
+	Name string
+	// AppliedAt es la última vez que se aplicó, cero si nunca se aplicó
+	AppliedAt time.Time
+	// Pending indica que Up la va a ejecutar: nunca se aplicó o su archivo
+	// cambió desde la última vez
+	Pending bool
+}
+
+// loadRepeatables lee las migraciones repetibles de Options.Source
+func (m *Migrator) loadRepeatables() ([]Repeatable, error) {
+	if m.opts.Source == nil {
+		return nil, nil
+	}
+	return LoadRepeatablesFS(m.opts.Source)
+}
+
+// applyRepeatables ejecuta, en orden de nombre, las migraciones repetibles
+// nuevas o cuyo checksum cambió desde la última vez que se aplicaron
+func (m *Migrator) applyRepeatables(ctx context.Context, result *Result) error {
+	repeatables, err := m.loadRepeatables()
+	if err != nil {
+		return fmt.Errorf("error cargando migraciones repetibles: %w", err)
+	}
+	if len(repeatables) == 0 {
+		return nil
+	}
+
+	stored, err := m.appliedScripts(ctx, m.repeatables)
+	if err != nil {
+		return fmt.Errorf("error obteniendo migraciones repetibles aplicadas: %w", err)
+	}
+
+	for _, r := range repeatables {
+		if row, ok := stored[r.Name]; ok && row.Checksum == r.Checksum() {
+			continue
+		}
+
+		if m.opts.DryRun {
+			m.log(ctx, slog.LevelInfo, "migración repetible planificada", m.repeatableAttrs(r, slog.String("sql", r.SQL))...)
+			result.Steps = append(result.Steps, Step{Name: r.Name, Direction: DirectionUp, SQL: r.SQL, Repeatable: true})
+			continue
+		}
+
+		m.log(ctx, slog.LevelInfo, "ejecutando migración repetible", m.repeatableAttrs(r)...)
+
+		start := time.Now()
+		if err := m.runRepeatable(ctx, m.repeatables, r); err != nil {
+			m.log(ctx, slog.LevelError, "migración repetible fallida", m.repeatableAttrs(r, slog.String("error", err.Error()))...)
+			return err
+		}
+		step := Step{Name: r.Name, Direction: DirectionUp, SQL: r.SQL, Repeatable: true, Duration: time.Since(start)}
+		result.Steps = append(result.Steps, step)
+
+		m.log(ctx, slog.LevelInfo, "migración repetible completada", m.repeatableAttrs(r, durationAttr(step.Duration))...)
+	}
+	return nil
+}
+
+// revertRepeatables ejecuta, en orden inverso de nombre, el script down de
+// cada migración repetible aplicada y la olvida. Si alguna aplicada no tiene
+// script down falla antes de cambiar nada: sus objetos pueden impedir revertir
+// las migraciones.
+func (m *Migrator) revertRepeatables(ctx context.Context, result *Result) error {
+	repeatables, err := m.loadRepeatables()
+	if err != nil {
+		return fmt.Errorf("error cargando migraciones repetibles: %w", err)
+	}
+	if len(repeatables) == 0 {
+		return nil
+	}
+
+	stored, err := m.appliedScripts(ctx, m.repeatables)
+	if err != nil {
+		return fmt.Errorf("error obteniendo migraciones repetibles aplicadas: %w", err)
+	}
+
+	var todo []Repeatable
+	var missing []string
+	for _, r := range slices.Backward(repeatables) {
+		if _, ok := stored[r.Name]; !ok {
+			continue
+		}
+		if r.DownSQL == "" {
+			missing = append(missing, "R_"+r.Name+".down.sql")
+		}
+		todo = append(todo, r)
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("faltan los scripts de limpieza de migraciones repetibles aplicadas: %s", strings.Join(missing, ", "))
+	}
+
+	for _, r := range todo {
+		if m.opts.DryRun {
+			m.log(ctx, slog.LevelInfo, "limpieza de migración repetible planificada", m.repeatableAttrs(r, slog.String("sql", r.DownSQL))...)
+			result.Steps = append(result.Steps, Step{Name: r.Name, Direction: DirectionDown, SQL: r.DownSQL, Repeatable: true})
+			continue
+		}
+
+		m.log(ctx, slog.LevelInfo, "revirtiendo migración repetible", m.repeatableAttrs(r)...)
+
+		start := time.Now()
+		if err := m.runRepeatableDown(ctx, r); err != nil {
+			m.log(ctx, slog.LevelError, "limpieza de migración repetible fallida", m.repeatableAttrs(r, slog.String("error", err.Error()))...)
+			return err
+		}
+		step := Step{Name: r.Name, Direction: DirectionDown, SQL: r.DownSQL, Repeatable: true, Duration: time.Since(start)}
+		result.Steps = append(result.Steps, step)
+
+		m.log(ctx, slog.LevelInfo, "migración repetible revertida", m.repeatableAttrs(r, durationAttr(step.Duration))...)
+	}
+	return nil
+}
+
+// runRepeatableDown ejecuta el script down y borra la fila de la migración
+// repetible, para que el próximo Up la vuelva a aplicar
+func (m *Migrator) runRepeatableDown(ctx context.Context, r Repeatable) error {
+	if r.DownNoTransaction {
+		conn, err := m.db.Connx(ctx)
+		if err != nil {
+			return fmt.Errorf("error obteniendo conexión: %w", err)
+		}
+		err = execScript(ctx, conn, r.DownSQL, m.dialect)
+		conn.Close()
+		if err != nil {
+			return fmt.Errorf("limpieza de repetible %s failed (sin transacción): %w", r.Name, err)
+		}
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %w", err)
+	}
+
+	if !r.DownNoTransaction {
+		if err := execScript(ctx, tx, r.DownSQL, m.dialect); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("limpieza de repetible %s failed: %w", r.Name, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM `+m.repeatables+` WHERE name = ?`), r.Name); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error olvidando migración repetible %s: %w", r.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error en commit: %w", err)
+	}
+	return nil
+}
+
+// appliedScripts devuelve las migraciones repetibles o los seeds aplicados
+// según table. En dry-run no crea la tabla.
+func (m *Migrator) appliedScripts(ctx context.Context, table string) (map[string]repeatableRow, error) {
+	if m.opts.DryRun {
+		return readRepeatableRows(ctx, m.db, table)
+	}
+	if err := ensureRepeatable(ctx, m.db, table); err != nil {
+		return nil, err
+	}
+	return repeatableRows(ctx, m.db, table)
+}
+
+// runRepeatable ejecuta el script y guarda su checksum en table. Si falla el
+// checksum anterior queda como estaba, así que se vuelve a intentar la
+// próxima vez.
+func (m *Migrator) runRepeatable(ctx context.Context, table string, r Repeatable) error {
+	if r.NoTransaction {
+		conn, err := m.db.Connx(ctx)
+		if err != nil {
+			return fmt.Errorf("error obteniendo conexión: %w", err)
+		}
+		err = execScript(ctx, conn, r.SQL, m.dialect)
+		conn.Close()
+		if err != nil {
+			return fmt.Errorf("repetible %s failed (sin transacción): %w", r.Name, err)
+		}
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %w", err)
+	}
+
+	if !r.NoTransaction {
+		if err := execScript(ctx, tx, r.SQL, m.dialect); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("repetible %s failed: %w", r.Name, err)
+		}
+	}
+	if err := recordRepeatable(ctx, tx, table, r); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error registrando migración repetible %s: %w", r.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error en commit: %w", err)
+	}
+	return nil
+}
+
+// repeatableStatus cruza las migraciones repetibles con su tabla de control
+func (m *Migrator) repeatableStatus(ctx context.Context) ([]RepeatableStatus, error) {
+	repeatables, err := m.loadRepeatables()
+	if err != nil {
+		return nil, fmt.Errorf("error cargando migraciones repetibles: %w", err)
+	}
+	if len(repeatables) == 0 {
+		return nil, nil
+	}
+
+	stored, err := readRepeatableRows(ctx, m.db, m.repeatables)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo migraciones repetibles aplicadas: %w", err)
+	}
+
+	statuses := make([]RepeatableStatus, 0, len(repeatables))
+	for _, r := range repeatables {
+		row, ok := stored[r.Name]
+		statuses = append(statuses, RepeatableStatus{
+			Name:      r.Name,
+			AppliedAt: row.AppliedAt.Time,
+			Pending:   !ok || row.Checksum != r.Checksum(),
+		})
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) repeatableAttrs(r Repeatable, extra ...slog.Attr) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("name", r.Name),
+		slog.Bool("repeatable", true),
+		slog.Bool("dry_run", m.opts.DryRun),
+	}
+	return append(attrs, extra...)
+}
@@ -0,0 +1,188 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRepeatableMigrations(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	source := fstest.MapFS{
+		"1_users.up.sql":     {Data: []byte("CREATE TABLE users (id INTEGER, active BOOLEAN);")},
+		"R_active_users.sql": {Data: []byte("DROP VIEW IF EXISTS active_users;\nCREATE VIEW active_users AS SELECT id FROM users WHERE active;")},
+		"R_user_count.sql":   {Data: []byte("DROP VIEW IF EXISTS user_count;\nCREATE VIEW user_count AS SELECT COUNT(*) AS n FROM users;")},
+		"2_tags.up.sql":      {Data: []byte("CREATE TABLE tags (id INTEGER);")},
+		"2_tags.down.sql":    {Data: []byte("DROP TABLE tags;")},
+	}
+
+	steps := func(result *Result) []string {
+		var names []string
+		for _, step := range result.Steps {
+			names = append(names, step.Name)
+		}
+		return names
+	}
+	assertSteps := func(t *testing.T, result *Result, expected ...string) {
+		t.Helper()
+		got := steps(result)
+		if len(got) != len(expected) {
+			t.Fatalf("expected steps %v, got %v", expected, got)
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Fatalf("expected steps %v, got %v", expected, got)
+			}
+		}
+	}
+
+	t.Run("status and dry run do not create the state table", func(t *testing.T) {
+		m, _ := New(db, Options{Source: source})
+		report, err := m.StatusReport(ctx)
+		if err != nil {
+			t.Fatalf("StatusReport failed: %v", err)
+		}
+		if len(report.Repeatables) != 2 || !report.Repeatables[0].Pending {
+			t.Errorf("expected 2 pending repeatables, got %+v", report.Repeatables)
+		}
+
+		dry, _ := New(db, Options{Source: source, DryRun: true})
+		result, err := dry.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up dry-run failed: %v", err)
+		}
+		assertSteps(t, result, "users", "tags", "active_users", "user_count")
+
+		if TableExists(t, db, "schema_migrations_repeatable") {
+			t.Error("read-only paths created the repeatable state table")
+		}
+	})
+
+	t.Run("applied after versioned migrations", func(t *testing.T) {
+		m, _ := New(db, Options{Source: source})
+		result, err := m.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+		assertSteps(t, result, "users", "tags", "active_users", "user_count")
+		if !result.Steps[2].Repeatable || result.Steps[2].Version != 0 || result.Steps[0].Repeatable {
+			t.Errorf("unexpected Repeatable flags: %+v", result.Steps)
+		}
+		if !TableExists(t, db, "schema_migrations_repeatable") {
+			t.Error("expected repeatable state table")
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2})
+	})
+
+	t.Run("unchanged repeatables are not re-run", func(t *testing.T) {
+		m, _ := New(db, Options{Source: source})
+		result, err := m.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+		assertSteps(t, result)
+	})
+
+	t.Run("re-run when changed", func(t *testing.T) {
+		source["R_active_users.sql"] = &fstest.MapFile{Data: []byte("DROP VIEW IF EXISTS active_users;\nCREATE VIEW active_users AS SELECT id AS user_id FROM users WHERE active;")}
+
+		dry, _ := New(db, Options{Source: source, DryRun: true})
+		result, err := dry.Up(ctx)
+		if err != nil {
+			t.Fatalf("dry-run Up failed: %v", err)
+		}
+		assertSteps(t, result, "active_users")
+
+		m, _ := New(db, Options{Source: source})
+		report, err := m.StatusReport(ctx)
+		if err != nil {
+			t.Fatalf("StatusReport failed: %v", err)
+		}
+		if len(report.Repeatables) != 2 || !report.Repeatables[0].Pending || report.Repeatables[1].Pending || report.Repeatables[0].AppliedAt.IsZero() {
+			t.Errorf("unexpected repeatable status: %+v", report.Repeatables)
+		}
+
+		result, err = m.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+		assertSteps(t, result, "active_users")
+		if _, err := db.Exec("SELECT user_id FROM active_users"); err != nil {
+			t.Errorf("view was not recreated: %v", err)
+		}
+	})
+
+	t.Run("failed repeatable is retried on next up", func(t *testing.T) {
+		source["R_user_count.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO missing VALUES (1);")}
+
+		m, _ := New(db, Options{Source: source})
+		if _, err := m.Up(ctx); err == nil {
+			t.Fatal("expected error from broken repeatable")
+		}
+
+		report, err := m.StatusReport(ctx)
+		if err != nil {
+			t.Fatalf("StatusReport failed: %v", err)
+		}
+		if !report.Repeatables[1].Pending {
+			t.Errorf("failed repeatable should stay pending: %+v", report.Repeatables)
+		}
+	})
+
+	t.Run("down and goto do not run them", func(t *testing.T) {
+		source["R_user_count.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+
+		m, _ := New(db, Options{Source: source})
+		result, err := m.Down(ctx, 1)
+		if err != nil {
+			t.Fatalf("Down failed: %v", err)
+		}
+		assertSteps(t, result, "tags")
+	})
+}
+
+func TestLoadRepeatables(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER);")},
+		"R_views.sql":      {Data: []byte("SELECT 1;")},
+		"R_functions.sql":  {Data: []byte("-- migrate:no-transaction\nSELECT 1;")},
+		"R_views.down.sql": {Data: []byte("DROP VIEW v;")},
+	}
+
+	migrations, err := LoadFS(fsys)
+	if err != nil {
+		t.Fatalf("LoadFS should ignore repeatables: %v", err)
+	}
+	if len(migrations) != 1 {
+		t.Errorf("expected 1 versioned migration, got %d", len(migrations))
+	}
+
+	repeatables, err := LoadRepeatablesFS(fsys)
+	if err != nil {
+		t.Fatalf("LoadRepeatablesFS failed: %v", err)
+	}
+	if len(repeatables) != 2 || repeatables[0].Name != "functions" || repeatables[1].Name != "views" {
+		t.Fatalf("unexpected repeatables: %+v", repeatables)
+	}
+	if !repeatables[0].NoTransaction || repeatables[1].NoTransaction {
+		t.Errorf("unexpected NoTransaction: %+v", repeatables)
+	}
+	if repeatables[0].DownSQL != "" || repeatables[1].DownSQL != "DROP VIEW v;" {
+		t.Errorf("unexpected DownSQL: %+v", repeatables)
+	}
+
+	// Un script de limpieza sin su repetible es un error de carga
+	var loadErr *LoadError
+	if _, err := LoadRepeatablesFS(fstest.MapFS{"R_gone.down.sql": {Data: []byte("DROP VIEW v;")}}); !errors.As(err, &loadErr) {
+		t.Errorf("expected LoadError for R_gone.down.sql, got %v", err)
+	}
+
+	// Un repetible con sufijo up/down no es válido
+	if _, err := LoadFS(fstest.MapFS{"R_views.up.sql": {Data: []byte("SELECT 1;")}}); err == nil {
+		t.Error("expected error for R_views.up.sql")
+	}
+}
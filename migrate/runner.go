@@ -2,292 +2,547 @@ package migrate
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// Up aplica todas las migraciones pendientes del directorio dir
 func Up(db *sqlx.DB, dir string, dryRun bool) error {
-	if dryRun {
-		fmt.Println("\n=== MODO DRY-RUN ACTIVADO ===")
-		fmt.Println("No se realizarán cambios en la base de datos")
+	return UpFS(db, os.DirFS(dir), dryRun)
+}
+
+// UpFS aplica todas las migraciones pendientes leídas de fsys
+func UpFS(db *sqlx.DB, fsys fs.FS, dryRun bool) error {
+	m, err := New(db, Options{Source: fsys, DryRun: dryRun})
+	if err != nil {
+		return err
 	}
+	_, err = m.Up(context.Background())
+	return err
+}
 
-	// En dry-run no necesitamos lock
-	if !dryRun {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+// Down revierte la última migración aplicada del directorio dir
+func Down(db *sqlx.DB, dir string, dryRun bool) error {
+	return DownFS(db, os.DirFS(dir), dryRun)
+}
 
-		locker, err := NewLocker(db)
-		if err != nil {
-			return fmt.Errorf("error creando locker: %w", err)
-		}
+// DownFS revierte la última migración aplicada leyendo los scripts de fsys
+func DownFS(db *sqlx.DB, fsys fs.FS, dryRun bool) error {
+	return DownNFS(db, fsys, 1, dryRun)
+}
 
-		if err := locker.Lock(ctx); err != nil {
-			return fmt.Errorf("no se pudo adquirir lock: %w", err)
-		}
-		defer locker.Unlock()
-	}
+// DownN revierte N migraciones
+func DownN(db *sqlx.DB, dir string, steps int, dryRun bool) error {
+	return DownNFS(db, os.DirFS(dir), steps, dryRun)
+}
 
-	if err := ensure(db); err != nil {
+// DownNFS revierte N migraciones leyendo los scripts de fsys
+func DownNFS(db *sqlx.DB, fsys fs.FS, steps int, dryRun bool) error {
+	m, err := New(db, Options{Source: fsys, DryRun: dryRun})
+	if err != nil {
 		return err
 	}
+	_, err = m.Down(context.Background(), steps)
+	return err
+}
 
-	migrations, err := Load(dir)
+// Goto lleva la base de datos a la versión indicada del directorio dir
+func Goto(db *sqlx.DB, dir string, version int, dryRun bool) error {
+	return GotoFS(db, os.DirFS(dir), version, dryRun)
+}
+
+// GotoFS lleva la base de datos a la versión indicada leyendo los scripts de fsys
+func GotoFS(db *sqlx.DB, fsys fs.FS, version int, dryRun bool) error {
+	m, err := New(db, Options{Source: fsys, DryRun: dryRun})
 	if err != nil {
 		return err
 	}
-	appliedVersions, err := applied(db)
+	_, err = m.Goto(context.Background(), version)
+	return err
+}
+
+// Redo revierte las últimas steps migraciones aplicadas del directorio dir y
+// las vuelve a aplicar
+func Redo(db *sqlx.DB, dir string, steps int, dryRun bool) error {
+	return RedoFS(db, os.DirFS(dir), steps, dryRun)
+}
+
+// RedoFS revierte y vuelve a aplicar las últimas steps migraciones leyendo
+// los scripts de fsys
+func RedoFS(db *sqlx.DB, fsys fs.FS, steps int, dryRun bool) error {
+	m, err := New(db, Options{Source: fsys, DryRun: dryRun})
 	if err != nil {
 		return err
 	}
-	done := map[int]bool{}
-	for _, v := range appliedVersions {
-		done[v] = true
-	}
+	_, err = m.Redo(context.Background(), steps)
+	return err
+}
 
-	pendingCount := 0
-	for _, m := range migrations {
-		if done[m.Version] {
-			continue
-		}
+// planner decide qué migraciones ejecutar, en orden, y en qué dirección a
+// partir de las migraciones cargadas y las versiones aplicadas
+type planner func(migrations []Migration, applied []int) ([]Migration, Direction, error)
 
-		pendingCount++
+// Up aplica todas las migraciones pendientes y después las repetibles nuevas
+// o modificadas. Cancelar ctx interrumpe la migración en curso y hace
+// rollback de su transacción.
+func (m *Migrator) Up(ctx context.Context) (*Result, error) {
+	return m.migrate(ctx, planUp, true)
+}
 
-		if dryRun {
-			fmt.Printf("[DRY-RUN] Se aplicaría migración %d: %s\n", m.Version, m.Name)
-			fmt.Println("\nContenido SQL:")
-			fmt.Println("---")
-			fmt.Println(m.UpSQL)
-			fmt.Println("---")
-			continue
-		}
+// Down revierte las últimas n migraciones aplicadas, de la más reciente a la
+// más antigua
+func (m *Migrator) Down(ctx context.Context, n int) (*Result, error) {
+	if n < 1 {
+		return &Result{DryRun: m.opts.DryRun}, errors.New("steps debe ser mayor a 0")
+	}
+	return m.migrate(ctx, planDown(n), false)
+}
 
-		fmt.Printf("Aplicando migración %d: %s\n", m.Version, m.Name)
+// Goto aplica las migraciones pendientes hasta version inclusive, o revierte
+// las aplicadas por encima de version, según dónde esté la base de datos.
+// Con version 0 revierte todas las migraciones aplicadas.
+func (m *Migrator) Goto(ctx context.Context, version int) (*Result, error) {
+	if version < 0 {
+		return &Result{DryRun: m.opts.DryRun}, errors.New("la versión no puede ser negativa")
+	}
+	return m.migrate(ctx, planGoto(version), false)
+}
 
-		tx, err := db.Beginx()
-		if err != nil {
-			return fmt.Errorf("error iniciando transacción: %w", err)
-		}
+// Redo revierte las últimas n migraciones aplicadas y vuelve a aplicar
+// exactamente esas, sin tocar otras pendientes, con un solo lock. Sirve para
+// iterar sobre una migración en desarrollo.
+func (m *Migrator) Redo(ctx context.Context, n int) (*Result, error) {
+	if n < 1 {
+		return &Result{DryRun: m.opts.DryRun}, errors.New("steps debe ser mayor a 0")
+	}
+	return m.locked(ctx, func(ctx context.Context, result *Result) error {
+		return m.redo(ctx, n, result)
+	})
+}
 
-		if _, err := tx.Exec(m.UpSQL); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("up %d failed: %w", m.Version, err)
-		}
+// migrate adquiere el lock, calcula el plan y lo ejecuta. Con repeatables
+// después corre las migraciones repetibles.
+func (m *Migrator) migrate(ctx context.Context, plan planner, repeatables bool) (*Result, error) {
+	return m.locked(ctx, func(ctx context.Context, result *Result) error {
+		return m.apply(ctx, plan, repeatables, result)
+	})
+}
 
-		// Usar Rebind para hacer la query portable entre drivers
-		query := db.Rebind(`INSERT INTO schema_migrations(version) VALUES(?)`)
-		if _, err := tx.Exec(query, m.Version); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error registrando migración %d: %w", m.Version, err)
-		}
+// locked corre fn con el lock de migraciones tomado. En dry-run no necesitamos
+// lock.
+func (m *Migrator) locked(ctx context.Context, fn func(ctx context.Context, result *Result) error) (*Result, error) {
+	result := &Result{DryRun: m.opts.DryRun}
 
-		if err := tx.Commit(); err != nil {
-			return err
+	if !m.opts.DryRun {
+		lockCtx, unlock, err := m.lock(ctx)
+		if err != nil {
+			return result, err
 		}
-
-		fmt.Printf("✓ Migración %d aplicada\n", m.Version)
+		defer unlock()
+		ctx = lockCtx
 	}
 
-	if dryRun {
-		if pendingCount == 0 {
-			fmt.Println("[DRY-RUN] No hay migraciones pendientes")
-		} else {
-			fmt.Printf("\n[DRY-RUN] Total: %d migración(es) pendiente(s)\n", pendingCount)
-			fmt.Println("[DRY-RUN] Ningún cambio fue aplicado a la base de datos")
-		}
+	return result, lockLost(ctx, fn(ctx, result))
+}
+
+// prepare deja lista la tabla de control y devuelve las migraciones
+// disponibles y las versiones aplicadas
+func (m *Migrator) prepare(ctx context.Context) ([]Migration, []int, error) {
+	if err := ensure(ctx, m.db, m.table); err != nil {
+		return nil, nil, err
 	}
 
-	return nil
-}
+	if err := m.checkDirty(ctx); err != nil {
+		return nil, nil, err
+	}
 
-func Down(db *sqlx.DB, dir string, dryRun bool) error {
-	if dryRun {
-		fmt.Println("\n=== MODO DRY-RUN ACTIVADO ===")
-		fmt.Println("No se realizarán cambios en la base de datos")
+	migrations, err := m.load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error cargando migraciones: %w", err)
 	}
 
-	// En dry-run no necesitamos lock
-	if !dryRun {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+	appliedVersions, err := applied(ctx, m.db, m.table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error obteniendo migraciones aplicadas: %w", err)
+	}
 
-		locker, err := NewLocker(db)
+	if m.opts.VerifyChecksums {
+		mismatches, err := m.verify(ctx, migrations)
 		if err != nil {
-			return fmt.Errorf("error creando locker: %w", err)
+			return nil, nil, err
 		}
-
-		if err := locker.Lock(ctx); err != nil {
-			return fmt.Errorf("no se pudo adquirir lock: %w", err)
+		if len(mismatches) > 0 {
+			return nil, nil, &ChecksumError{Mismatches: mismatches}
 		}
-		defer locker.Unlock()
 	}
+	return migrations, appliedVersions, nil
+}
 
-	migrations, err := Load(dir)
+// apply calcula el plan sobre el estado actual y lo ejecuta
+func (m *Migrator) apply(ctx context.Context, plan planner, repeatables bool, result *Result) error {
+	migrations, appliedVersions, err := m.prepare(ctx)
 	if err != nil {
-		return errors.New("no migration to rollback")
+		return err
 	}
-	v, err := last(db)
+
+	todo, dir, err := plan(migrations, appliedVersions)
 	if err != nil {
-		return errors.New("no migration to rollback")
+		return err
 	}
 
-	var target *Migration
-	for _, m := range migrations {
-		if m.Version == v {
-			target = &m
-			break
-		}
+	latest := latestVersion(appliedVersions)
+	if err := m.checkOrder(todo, dir, latest); err != nil {
+		return err
 	}
 
-	if target == nil || target.DownSQL == "" {
-		return errors.New("no down migration found")
+	return m.withHooks(ctx, dir, result, func(h *hooks) error {
+		if err := m.execute(ctx, todo, dir, latest, h, result); err != nil {
+			return err
+		}
+		if repeatables {
+			return m.applyRepeatables(ctx, result)
+		}
+		return nil
+	})
+}
+
+// redo revierte las últimas n migraciones y las vuelve a aplicar en orden
+// ascendente. En dry-run las dos mitades del plan salen del mismo estado.
+func (m *Migrator) redo(ctx context.Context, n int, result *Result) error {
+	migrations, appliedVersions, err := m.prepare(ctx)
+	if err != nil {
+		return err
 	}
 
-	if dryRun {
-		fmt.Printf("[DRY-RUN] Se revertiría migración %d: %s\n", target.Version, target.Name)
-		fmt.Println("\nContenido SQL:")
-		fmt.Println("---")
-		fmt.Println(target.DownSQL)
-		fmt.Println("---")
-		fmt.Println("\n[DRY-RUN] Ningún cambio fue aplicado a la base de datos")
-		return nil
+	down, _, err := planDown(n)(migrations, appliedVersions)
+	if err != nil {
+		return err
 	}
+	up := slices.Clone(down)
+	slices.Reverse(up)
 
-	fmt.Printf("Revirtiendo migración %d: %s\n", target.Version, target.Name)
+	// Las revertidas son las de versión más alta, así que al volver a
+	// aplicarlas ninguna queda fuera de orden
+	return m.withHooks(ctx, "", result, func(h *hooks) error {
+		if err := m.execute(ctx, down, DirectionDown, 0, h, result); err != nil {
+			return err
+		}
+		return m.execute(ctx, up, DirectionUp, 0, h, result)
+	})
+}
 
-	tx, err := db.Beginx()
-	if err != nil {
-		return fmt.Errorf("error iniciando transacción: %w", err)
+// withHooks corre fn entre los hooks BeforeAll y AfterAll, y OnError si algo
+// falla. En dry-run no corre ningún hook.
+func (m *Migrator) withHooks(ctx context.Context, dir Direction, result *Result, fn func(h *hooks) error) error {
+	var h *hooks
+	if !m.opts.DryRun {
+		var err error
+		if h, err = m.hooks(); err != nil {
+			return err
+		}
 	}
 
-	if _, err := tx.Exec(target.DownSQL); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error ejecutando down migration: %w", err)
+	start := time.Now()
+	err := h.beforeAll(ctx, dir)
+	if err == nil {
+		err = fn(h)
+	}
+	if err == nil {
+		err = h.afterAll(ctx, dir, time.Since(start), result.Steps)
 	}
+	if err != nil {
+		h.onError(ctx, HookEvent{Direction: dir, Err: err, Steps: result.Steps})
+	}
+	return err
+}
 
-	// Usar Rebind para hacer la query portable entre drivers
-	query := db.Rebind(`DELETE FROM schema_migrations WHERE version = ?`)
-	if _, err := tx.Exec(query, target.Version); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error eliminando registro de migración: %w", err)
+// execute corre el plan en orden, o solo lo informa en dry-run. latest es la
+// última versión aplicada antes de empezar: las migraciones up anteriores se
+// registran como fuera de orden. Si una migración falla llama a OnError.
+func (m *Migrator) execute(ctx context.Context, plan []Migration, dir Direction, latest int, h *hooks, result *Result) error {
+	for _, mig := range plan {
+		outOfOrder := dir == DirectionUp && mig.Version < latest
+
+		if m.opts.DryRun {
+			m.log(ctx, slog.LevelInfo, "migración planificada", m.migrationAttrs(mig, dir, slog.String("sql", mig.script(dir)))...)
+			result.Steps = append(result.Steps, Step{Version: mig.Version, Name: mig.Name, Direction: dir, SQL: mig.script(dir), OutOfOrder: outOfOrder})
+			continue
+		}
+
+		if outOfOrder {
+			m.log(ctx, slog.LevelWarn, "aplicando migración fuera de orden", m.migrationAttrs(mig, dir, slog.Int("latest", latest))...)
+		}
+		m.log(ctx, slog.LevelInfo, "ejecutando migración", m.migrationAttrs(mig, dir)...)
+
+		start := time.Now()
+		step, err := m.run(ctx, mig, dir, outOfOrder, h)
+		if err != nil {
+			m.log(ctx, slog.LevelError, "migración fallida", m.migrationAttrs(mig, dir, slog.String("error", err.Error()))...)
+			h.onError(ctx, HookEvent{Migration: mig, Direction: dir, Duration: time.Since(start), Err: err, Steps: result.Steps})
+			return err
+		}
+		result.Steps = append(result.Steps, step)
+
+		m.log(ctx, slog.LevelInfo, "migración completada", m.migrationAttrs(mig, dir, durationAttr(step.Duration))...)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return err
+	if m.opts.DryRun {
+		m.log(ctx, slog.LevelInfo, "dry-run: ningún cambio fue aplicado",
+			slog.String("direction", string(dir)), slog.Int("total", len(plan)), slog.Bool("dry_run", true))
 	}
 
-	fmt.Printf("✓ Migración %d revertida\n", target.Version)
 	return nil
 }
 
-// DownN revierte N migraciones
-func DownN(db *sqlx.DB, dir string, steps int, dryRun bool) error {
-	if dryRun {
-		fmt.Println("\n=== MODO DRY-RUN ACTIVADO ===")
-		fmt.Println("No se realizarán cambios en la base de datos")
+// planUp devuelve todas las migraciones pendientes en orden ascendente
+func planUp(migrations []Migration, appliedVersions []int) ([]Migration, Direction, error) {
+	done := map[int]bool{}
+	for _, v := range appliedVersions {
+		done[v] = true
 	}
 
-	if steps < 1 {
-		return errors.New("steps debe ser mayor a 0")
+	var plan []Migration
+	for _, mig := range migrations {
+		if !done[mig.Version] {
+			plan = append(plan, mig)
+		}
 	}
+	return plan, DirectionUp, nil
+}
 
-	// En dry-run no necesitamos lock
-	if !dryRun {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		locker, err := NewLocker(db)
-		if err != nil {
-			return fmt.Errorf("error creando locker: %w", err)
+// planDown devuelve las últimas n migraciones aplicadas, de la más reciente
+// a la más antigua
+func planDown(n int) planner {
+	return func(migrations []Migration, appliedVersions []int) ([]Migration, Direction, error) {
+		if len(appliedVersions) == 0 {
+			return nil, DirectionDown, errors.New("no hay migraciones para revertir")
 		}
 
-		if err := locker.Lock(ctx); err != nil {
-			return fmt.Errorf("no se pudo adquirir lock: %w", err)
+		// Validar que no se intente revertir más de las aplicadas
+		if n > len(appliedVersions) {
+			return nil, DirectionDown, fmt.Errorf("solo hay %d migraci(ones) aplicada(s), no se pueden revertir %d", len(appliedVersions), n)
 		}
-		defer locker.Unlock()
-	}
 
-	migrations, err := Load(dir)
-	if err != nil {
-		return fmt.Errorf("error cargando migraciones: %w", err)
+		return revertible(migrations, appliedVersions[len(appliedVersions)-n:])
 	}
+}
 
-	appliedVersions, err := applied(db)
-	if err != nil {
-		return fmt.Errorf("error obteniendo migraciones aplicadas: %w", err)
-	}
+// planGoto calcula la dirección a partir de la versión objetivo: si hay
+// migraciones aplicadas por encima de ella las revierte, si no aplica las
+// pendientes hasta ella inclusive
+func planGoto(target int) planner {
+	return func(migrations []Migration, appliedVersions []int) ([]Migration, Direction, error) {
+		if target > 0 && !hasVersion(migrations, target) {
+			return nil, DirectionUp, fmt.Errorf("migración %d no encontrada", target)
+		}
 
-	if len(appliedVersions) == 0 {
-		return errors.New("no hay migraciones para revertir")
-	}
+		var above []int
+		for _, v := range appliedVersions {
+			if v > target {
+				above = append(above, v)
+			}
+		}
+		if len(above) > 0 {
+			return revertible(migrations, above)
+		}
 
-	// Validar que no se intente revertir más de las aplicadas
-	if steps > len(appliedVersions) {
-		return fmt.Errorf("solo hay %d migraci(ones) aplicada(s), no se pueden revertir %d", len(appliedVersions), steps)
+		pending, _, _ := planUp(migrations, appliedVersions)
+		var plan []Migration
+		for _, mig := range pending {
+			if mig.Version <= target {
+				plan = append(plan, mig)
+			}
+		}
+		return plan, DirectionUp, nil
 	}
+}
 
+// revertible arma el plan para revertir las versiones dadas (en orden
+// ascendente), empezando por la más reciente. Falla si alguna no tiene
+// archivo o script down.
+func revertible(migrations []Migration, versions []int) ([]Migration, Direction, error) {
 	// Crear mapa de migraciones por versión
 	migrationMap := make(map[int]*Migration)
 	for i := range migrations {
 		migrationMap[migrations[i].Version] = &migrations[i]
 	}
 
-	// Obtener las últimas N versiones aplicadas (en orden descendente)
-	toRevert := appliedVersions[len(appliedVersions)-steps:]
+	var plan []Migration
 	// Invertir para revertir desde la más reciente
-	for i := len(toRevert) - 1; i >= 0; i-- {
-		version := toRevert[i]
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := versions[i]
 		target, exists := migrationMap[version]
 
 		if !exists {
-			return fmt.Errorf("archivo de migración %d no encontrado", version)
+			return nil, DirectionDown, fmt.Errorf("archivo de migración %d no encontrado", version)
 		}
 
-		if target.DownSQL == "" {
-			return fmt.Errorf("migración %d no tiene script down", version)
+		if !target.hasDown() {
+			return nil, DirectionDown, fmt.Errorf("migración %d no tiene script down", version)
 		}
 
-		if dryRun {
-			fmt.Printf("[DRY-RUN] Se revertiría migración %d: %s\n", target.Version, target.Name)
-			fmt.Println("\nContenido SQL:")
-			fmt.Println("---")
-			fmt.Println(target.DownSQL)
-			fmt.Println("---")
-			continue
+		plan = append(plan, *target)
+	}
+	return plan, DirectionDown, nil
+}
+
+func hasVersion(migrations []Migration, version int) bool {
+	for _, mig := range migrations {
+		if mig.Version == version {
+			return true
 		}
+	}
+	return false
+}
 
-		fmt.Printf("Revirtiendo migración %d: %s\n", target.Version, target.Name)
+// run ejecuta una migración en la dirección indicada dentro de una
+// transacción, junto con la actualización de la tabla de control. Antes de
+// empezar la marca como dirty; si falla y no es seguro asumir que el rollback
+// deshizo todo, la marca queda puesta. outOfOrder queda registrado en la
+// tabla de control al aplicarla. Los hooks BeforeEach y AfterEach corren en la
+// misma sesión que el script.
+func (m *Migrator) run(ctx context.Context, mig Migration, dir Direction, outOfOrder bool, h *hooks) (Step, error) {
+	start := time.Now()
+	step := Step{Version: mig.Version, Name: mig.Name, Direction: dir, SQL: mig.script(dir), OutOfOrder: outOfOrder}
+
+	if err := markDirty(ctx, m.db, m.table, mig.Version, dir, outOfOrder); err != nil {
+		return step, fmt.Errorf("error marcando migración %d como dirty: %w", mig.Version, err)
+	}
 
-		tx, err := db.Beginx()
-		if err != nil {
-			return fmt.Errorf("error iniciando transacción: %w", err)
+	var err error
+	if mig.noTransaction(dir) {
+		err = m.runNoTx(ctx, mig, dir, h)
+	} else {
+		err = m.runTx(ctx, mig, dir, h)
+	}
+	if err == nil {
+		step.Duration = time.Since(start)
+		return step, nil
+	}
+
+	// Con DDL transaccional el rollback dejó la base como estaba, y si falló
+	// BeforeEach el script no llegó a correr: en los dos casos la marca se
+	// puede quitar. Se usa un contexto propio por si ctx fue cancelado.
+	var before *beforeEachError
+	if errors.As(err, &before) || transactionalDDL(m.dialect) && !mig.noTransaction(dir) {
+		if cerr := clearDirty(context.WithoutCancel(ctx), m.db, m.table, mig.Version, dir); cerr == nil {
+			return step, err
 		}
+	}
+	return step, fmt.Errorf("%w (la migración %d quedó marcada como dirty)", err, mig.Version)
+}
+
+func (m *Migrator) runTx(ctx context.Context, mig Migration, dir Direction, h *hooks) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %w", err)
+	}
 
-		if _, err := tx.Exec(target.DownSQL); err != nil {
+	exec := func() error { return m.exec(ctx, tx, mig, dir) }
+	if dir == DirectionUp {
+		if err := h.around(ctx, tx, mig, dir, exec); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("error ejecutando down migration %d: %w", target.Version, err)
+			return fmt.Errorf("up %d failed: %w", mig.Version, err)
 		}
-
-		query := db.Rebind(`DELETE FROM schema_migrations WHERE version = ?`)
-		if _, err := tx.Exec(query, target.Version); err != nil {
+		if err := record(ctx, tx, m.table, mig); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("error eliminando registro de migración %d: %w", target.Version, err)
+			return fmt.Errorf("error registrando migración %d: %w", mig.Version, err)
 		}
-
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("error en commit: %w", err)
+	} else {
+		if err := h.around(ctx, tx, mig, dir, exec); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error ejecutando down migration %d: %w", mig.Version, err)
 		}
+		if err := unrecord(ctx, tx, m.table, mig.Version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error eliminando registro de migración %d: %w", mig.Version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error en commit: %w", err)
+	}
+	return nil
+}
+
+// runNoTx ejecuta el script fuera de una transacción y después actualiza la
+// tabla de control. Si el script falla no hay rollback posible, así que la
+// marca dirty puesta por run queda como registro del fallo.
+func (m *Migrator) runNoTx(ctx context.Context, mig Migration, dir Direction, h *hooks) error {
+	// Una sola conexión para que las sentencias y los hooks compartan la sesión
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("error obteniendo conexión: %w", err)
+	}
+	err = h.around(ctx, conn, mig, dir, func() error {
+		return execScript(ctx, conn, mig.script(dir), m.dialect)
+	})
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("%s %d failed (sin transacción): %w", dir, mig.Version, err)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %w", err)
+	}
+
+	if dir == DirectionUp {
+		err = record(ctx, tx, m.table, mig)
+	} else {
+		err = unrecord(ctx, tx, m.table, mig.Version)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error actualizando registro de migración %d: %w", mig.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error en commit: %w", err)
+	}
+	return nil
+}
 
-		fmt.Printf("✓ Migración %d revertida\n", target.Version)
+// exec ejecuta el cuerpo de la migración: la función Go si la tiene, o su SQL
+// sentencia por sentencia
+func (m *Migrator) exec(ctx context.Context, tx *sqlx.Tx, mig Migration, dir Direction) error {
+	if fn := mig.fn(dir); fn != nil {
+		return fn(ctx, tx)
 	}
+	return execScript(ctx, tx, mig.script(dir), m.dialect)
+}
 
-	if dryRun {
-		fmt.Printf("\n[DRY-RUN] Total: %d migración(es) se revertirían\n", steps)
-		fmt.Println("[DRY-RUN] Ningún cambio fue aplicado a la base de datos")
+// execer es lo que tienen en común *sqlx.Tx, *sqlx.Conn y *sqlx.DB
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// execScript divide el script en sentencias y las ejecuta en orden. Si una
+// falla devuelve un *StatementError con su número y línea.
+func execScript(ctx context.Context, ex execer, script string, dialect Dialect) error {
+	statements, err := SplitStatements(script, dialect)
+	if err != nil {
+		return err
 	}
 
+	for i, stmt := range statements {
+		if _, err := ex.ExecContext(ctx, stmt.SQL); err != nil {
+			return &StatementError{Index: i + 1, Line: stmt.Line, Err: err}
+		}
+	}
 	return nil
 }
+
+// transactionalDDL indica si el motor hace rollback también de las
+// sentencias DDL. En MySQL un CREATE/ALTER confirma la transacción implícitamente.
+func transactionalDDL(dialect Dialect) bool {
+	return dialect == DialectPostgres || dialect == DialectSQLite
+}
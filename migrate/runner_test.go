@@ -1,6 +1,10 @@
 package migrate
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
 	"testing"
 )
 
@@ -316,3 +320,227 @@ func TestTransactionalRollback(t *testing.T) {
 		}
 	})
 }
+
+func TestUpFSAndDownNFS(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	fsys := os.DirFS(SetupTestMigrations(t))
+
+	t.Run("apply from fs", func(t *testing.T) {
+		if err := UpFS(db, fsys, false); err != nil {
+			t.Fatalf("UpFS failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1, 2, 3})
+	})
+
+	t.Run("revert from fs", func(t *testing.T) {
+		if err := DownNFS(db, fsys, 2, false); err != nil {
+			t.Fatalf("DownNFS failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1})
+	})
+}
+
+func TestGoto(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	dir := SetupTestMigrations(t)
+
+	t.Run("goto forward applies up to target", func(t *testing.T) {
+		if err := Goto(db, dir, 2, false); err != nil {
+			t.Fatalf("Goto failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1, 2})
+		if IndexExists(t, db, "idx_posts_user_id") {
+			t.Error("migration 3 should not be applied")
+		}
+	})
+
+	t.Run("goto current version is a no-op", func(t *testing.T) {
+		if err := Goto(db, dir, 2, false); err != nil {
+			t.Fatalf("Goto failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1, 2})
+	})
+
+	t.Run("goto backward reverts above target", func(t *testing.T) {
+		if err := Up(db, dir, false); err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+
+		if err := Goto(db, dir, 1, false); err != nil {
+			t.Fatalf("Goto failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1})
+		if TableExists(t, db, "posts") {
+			t.Error("tabla posts no fue eliminada")
+		}
+	})
+
+	t.Run("goto zero reverts everything", func(t *testing.T) {
+		if err := Goto(db, dir, 0, false); err != nil {
+			t.Fatalf("Goto failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{})
+	})
+
+	t.Run("unknown target version", func(t *testing.T) {
+		if err := Goto(db, dir, 42, false); err == nil {
+			t.Error("esperaba error con versión inexistente")
+		}
+	})
+}
+
+func TestGotoDryRun(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	dir := SetupTestMigrations(t)
+
+	if err := Up(db, dir, false); err != nil {
+		t.Fatalf("setup Up failed: %v", err)
+	}
+
+	m, _ := New(db, Options{Source: os.DirFS(dir), DryRun: true})
+
+	t.Run("dry run returns the plan without reverting", func(t *testing.T) {
+		result, err := m.Goto(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("Goto dry-run failed: %v", err)
+		}
+
+		if !result.DryRun || len(result.Steps) != 2 {
+			t.Fatalf("unexpected plan: %+v", result)
+		}
+		if result.Steps[0].Version != 3 || result.Steps[1].Version != 2 || result.Steps[0].Direction != DirectionDown {
+			t.Errorf("unexpected plan order: %+v", result.Steps)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1, 2, 3})
+	})
+}
+
+func TestNoTransactionMigration(t *testing.T) {
+	t.Run("runs statements that fail inside a transaction", func(t *testing.T) {
+		db := SetupTestDB(t)
+		defer db.Close()
+
+		dir := SetupTestMigrations(t)
+		CreateMigrationFile(t, dir, "4_vacuum.up.sql", "-- migrate:no-transaction\nVACUUM;\n")
+
+		if err := Up(db, dir, false); err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+
+		AssertMigrationsApplied(t, db, []int{1, 2, 3, 4})
+	})
+
+	t.Run("fails inside a transaction without the directive", func(t *testing.T) {
+		db := SetupTestDB(t)
+		defer db.Close()
+
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "1_vacuum.up.sql", "VACUUM;\n")
+
+		if err := Up(db, dir, false); err == nil {
+			t.Fatal("esperaba error ejecutando VACUUM dentro de una transacción")
+		}
+	})
+
+	t.Run("failure leaves the migration dirty", func(t *testing.T) {
+		db := SetupTestDB(t)
+		defer db.Close()
+
+		dir := t.TempDir()
+		CreateMigrationFile(t, dir, "1_broken.up.sql", "-- migrate:no-transaction\nINVALID SQL HERE;\n")
+
+		if err := Up(db, dir, false); err == nil {
+			t.Fatal("esperaba error con SQL inválido")
+		}
+
+		var dirtyErr *DirtyError
+		if err := Up(db, dir, false); !errors.As(err, &dirtyErr) || dirtyErr.Version != 1 {
+			t.Errorf("expected *DirtyError for version 1, got %v", err)
+		}
+	})
+}
+
+func TestRedo(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	dir := SetupTestMigrations(t)
+	m, _ := New(db, Options{Source: os.DirFS(dir)})
+
+	if _, err := m.Goto(ctx, 2); err != nil {
+		t.Fatalf("setup Goto failed: %v", err)
+	}
+
+	plan := func(result *Result) []string {
+		var steps []string
+		for _, step := range result.Steps {
+			steps = append(steps, fmt.Sprintf("%s %d", step.Direction, step.Version))
+		}
+		return steps
+	}
+
+	t.Run("redo reapplies only the reverted migrations", func(t *testing.T) {
+		db.MustExec("INSERT INTO users (id, email, name) VALUES (1, 'a@example.com', 'A')")
+
+		result, err := m.Redo(ctx, 2)
+		if err != nil {
+			t.Fatalf("Redo failed: %v", err)
+		}
+
+		expected := []string{"down 2", "down 1", "up 1", "up 2"}
+		if fmt.Sprint(plan(result)) != fmt.Sprint(expected) {
+			t.Errorf("expected %v, got %v", expected, plan(result))
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2})
+		if IndexExists(t, db, "idx_posts_user_id") {
+			t.Error("pending migration 3 should not be applied")
+		}
+
+		var users int
+		db.Get(&users, "SELECT COUNT(*) FROM users")
+		if users != 0 {
+			t.Error("users table should have been recreated")
+		}
+	})
+
+	t.Run("dry run returns both halves of the plan", func(t *testing.T) {
+		dry, _ := New(db, Options{Source: os.DirFS(dir), DryRun: true})
+		result, err := dry.Redo(ctx, 1)
+		if err != nil {
+			t.Fatalf("Redo dry-run failed: %v", err)
+		}
+
+		expected := []string{"down 2", "up 2"}
+		if fmt.Sprint(plan(result)) != fmt.Sprint(expected) {
+			t.Errorf("expected %v, got %v", expected, plan(result))
+		}
+		if result.Steps[0].SQL == "" || result.Steps[1].SQL == "" {
+			t.Error("dry-run steps should include the SQL")
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2})
+	})
+
+	t.Run("invalid steps", func(t *testing.T) {
+		if _, err := m.Redo(ctx, 0); err == nil {
+			t.Error("esperaba error con steps 0")
+		}
+		if err := Redo(db, dir, 3, false); err == nil {
+			t.Error("esperaba error al rehacer más migraciones que las aplicadas")
+		}
+		AssertMigrationsApplied(t, db, []int{1, 2})
+	})
+}
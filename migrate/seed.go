@@ -0,0 +1,147 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Seed es un archivo de datos de referencia o de demo de Options.Seeds. Se
+// ejecuta como una migración repetible: la primera vez y cada vez que su
+// contenido cambia, así que el script tiene que ser idempotente.
+type Seed struct {
+	Repeatable
+	// Envs son los entornos en los que se puede ejecutar, de la directiva
+	// "-- migrate:env dev,staging". Vacío permite todos.
+	Envs []string
+}
+
+// allowed indica si el seed se puede ejecutar en el entorno env. Un seed
+// restringido nunca corre sin entorno.
+func (s Seed) allowed(env string) bool {
+	return len(s.Envs) == 0 || slices.Contains(s.Envs, env)
+}
+
+// LoadSeeds lee los seeds de un directorio
+func LoadSeeds(dir string) ([]Seed, error) {
+	return LoadSeedsFS(os.DirFS(dir))
+}
+
+// LoadSeedsFS lee los archivos <nombre>.sql de la raíz de fsys, ordenados por
+// nombre
+func LoadSeedsFS(fsys fs.FS) ([]Seed, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Seed
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), ".sql")
+		if f.IsDir() || !ok {
+			continue
+		}
+
+		sql, err := fs.ReadFile(fsys, f.Name())
+		if err != nil {
+			return nil, &LoadError{Files: []FileError{{f.Name(), fmt.Sprintf("no se pudo leer: %v", err)}}}
+		}
+
+		directives := headerDirectives(string(sql))
+		_, noTx := directives["no-transaction"]
+		seed := Seed{Repeatable: Repeatable{Name: name, SQL: string(sql), NoTransaction: noTx}}
+		if envs, ok := directives["env"]; ok {
+			seed.Envs = strings.FieldsFunc(envs, func(r rune) bool { return r == ',' || r == ' ' })
+			if len(seed.Envs) == 0 {
+				return nil, &LoadError{Files: []FileError{{f.Name(), "la directiva migrate:env necesita al menos un entorno"}}}
+			}
+		}
+		out = append(out, seed)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// Seed ejecuta, en orden de nombre, los seeds de Options.Seeds que son nuevos
+// o cambiaron desde la última vez. Los restringidos a otros entornos con
+// "-- migrate:env" no se ejecutan y quedan en Result.Skipped. Falla si hay
+// una migración dirty.
+func (m *Migrator) Seed(ctx context.Context, env string) (*Result, error) {
+	if m.opts.Seeds == nil {
+		return &Result{DryRun: m.opts.DryRun}, errors.New("Options.Seeds es obligatorio para ejecutar seeds")
+	}
+
+	return m.locked(ctx, func(ctx context.Context, result *Result) error {
+		return m.seed(ctx, env, result)
+	})
+}
+
+func (m *Migrator) seed(ctx context.Context, env string, result *Result) error {
+	seeds, err := LoadSeedsFS(m.opts.Seeds)
+	if err != nil {
+		return fmt.Errorf("error cargando seeds: %w", err)
+	}
+
+	// Los seeds van después del schema: una migración a medias lo deja en un
+	// estado desconocido
+	if err := ensure(ctx, m.db, m.table); err != nil {
+		return err
+	}
+	if err := m.checkDirty(ctx); err != nil {
+		return err
+	}
+
+	stored, err := m.appliedScripts(ctx, m.seeds)
+	if err != nil {
+		return fmt.Errorf("error obteniendo seeds aplicados: %w", err)
+	}
+
+	for _, s := range seeds {
+		if !s.allowed(env) {
+			m.log(ctx, slog.LevelInfo, "seed omitido por entorno", m.seedAttrs(s, env, slog.String("envs", strings.Join(s.Envs, ",")))...)
+			result.Skipped = append(result.Skipped, s.Name)
+			continue
+		}
+		if row, ok := stored[s.Name]; ok && row.Checksum == s.Checksum() {
+			continue
+		}
+
+		if m.opts.DryRun {
+			m.log(ctx, slog.LevelInfo, "seed planificado", m.seedAttrs(s, env, slog.String("sql", s.SQL))...)
+			result.Steps = append(result.Steps, Step{Name: s.Name, Direction: DirectionUp, SQL: s.SQL})
+			continue
+		}
+
+		m.log(ctx, slog.LevelInfo, "ejecutando seed", m.seedAttrs(s, env)...)
+
+		start := time.Now()
+		if err := m.runRepeatable(ctx, m.seeds, s.Repeatable); err != nil {
+			m.log(ctx, slog.LevelError, "seed fallido", m.seedAttrs(s, env, slog.String("error", err.Error()))...)
+			return err
+		}
+		step := Step{Name: s.Name, Direction: DirectionUp, SQL: s.SQL, Duration: time.Since(start)}
+		result.Steps = append(result.Steps, step)
+
+		m.log(ctx, slog.LevelInfo, "seed completado", m.seedAttrs(s, env, durationAttr(step.Duration))...)
+	}
+	return nil
+}
+
+func (m *Migrator) seedAttrs(s Seed, env string, extra ...slog.Attr) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("seed", s.Name),
+		slog.String("env", env),
+		slog.Bool("dry_run", m.opts.DryRun),
+	}
+	return append(attrs, extra...)
+}
@@ -0,0 +1,134 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSeed(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	source := fstest.MapFS{
+		"1_countries.up.sql": {Data: []byte("CREATE TABLE countries (code TEXT PRIMARY KEY, name TEXT);")},
+		"2_users.up.sql":     {Data: []byte("CREATE TABLE users (email TEXT PRIMARY KEY);")},
+	}
+	seeds := fstest.MapFS{
+		"01_countries.sql":  {Data: []byte("INSERT OR REPLACE INTO countries VALUES ('AR', 'Argentina');")},
+		"02_demo_users.sql": {Data: []byte("-- migrate:env dev, staging\nINSERT OR IGNORE INTO users VALUES ('demo@example.com');")},
+	}
+
+	m, _ := New(db, Options{Source: source, Seeds: seeds})
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	count := func(table string) int {
+		var n int
+		db.Get(&n, "SELECT COUNT(*) FROM "+table)
+		return n
+	}
+
+	t.Run("dry run does not create the state table", func(t *testing.T) {
+		dry, _ := New(db, Options{Source: source, Seeds: seeds, DryRun: true})
+		result, err := dry.Seed(ctx, "dev")
+		if err != nil {
+			t.Fatalf("dry-run Seed failed: %v", err)
+		}
+		if len(result.Steps) != 2 {
+			t.Errorf("expected both seeds planned, got %+v", result.Steps)
+		}
+		if TableExists(t, db, "schema_migrations_seeds") {
+			t.Error("dry-run created the seeds state table")
+		}
+	})
+
+	t.Run("seeds for other environments are skipped", func(t *testing.T) {
+		result, err := m.Seed(ctx, "prod")
+		if err != nil {
+			t.Fatalf("Seed failed: %v", err)
+		}
+		if len(result.Steps) != 1 || result.Steps[0].Name != "01_countries" {
+			t.Errorf("expected only 01_countries, got %+v", result.Steps)
+		}
+		if len(result.Skipped) != 1 || result.Skipped[0] != "02_demo_users" {
+			t.Errorf("expected 02_demo_users skipped, got %v", result.Skipped)
+		}
+		if count("users") != 0 {
+			t.Error("demo users should not be loaded in prod")
+		}
+	})
+
+	t.Run("restricted seeds are skipped without an environment", func(t *testing.T) {
+		result, err := m.Seed(ctx, "")
+		if err != nil {
+			t.Fatalf("Seed failed: %v", err)
+		}
+		if len(result.Steps) != 0 || len(result.Skipped) != 1 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("only new or changed seeds run", func(t *testing.T) {
+		result, err := m.Seed(ctx, "dev")
+		if err != nil {
+			t.Fatalf("Seed failed: %v", err)
+		}
+		if len(result.Steps) != 1 || result.Steps[0].Name != "02_demo_users" || len(result.Skipped) != 0 {
+			t.Errorf("expected only 02_demo_users, got %+v", result)
+		}
+
+		seeds["01_countries.sql"] = &fstest.MapFile{Data: []byte("INSERT OR REPLACE INTO countries VALUES ('AR', 'Argentina');\nINSERT OR REPLACE INTO countries VALUES ('UY', 'Uruguay');")}
+
+		dry, _ := New(db, Options{Source: source, Seeds: seeds, DryRun: true})
+		result, err = dry.Seed(ctx, "dev")
+		if err != nil {
+			t.Fatalf("dry-run Seed failed: %v", err)
+		}
+		if len(result.Steps) != 1 || result.Steps[0].Name != "01_countries" || count("countries") != 1 {
+			t.Errorf("dry-run should only plan 01_countries: %+v", result.Steps)
+		}
+
+		result, err = m.Seed(ctx, "dev")
+		if err != nil {
+			t.Fatalf("Seed failed: %v", err)
+		}
+		if len(result.Steps) != 1 || count("countries") != 2 || count("users") != 1 {
+			t.Errorf("unexpected result: %+v", result.Steps)
+		}
+	})
+
+	t.Run("fails with a dirty migration", func(t *testing.T) {
+		db.MustExec(`INSERT INTO schema_migrations (version, dirty) VALUES (3, TRUE)`)
+		defer db.MustExec(`DELETE FROM schema_migrations WHERE version = 3`)
+
+		var dirtyErr *DirtyError
+		if _, err := m.Seed(ctx, "dev"); !errors.As(err, &dirtyErr) {
+			t.Errorf("expected DirtyError, got %v", err)
+		}
+	})
+}
+
+func TestLoadSeeds(t *testing.T) {
+	seeds, err := LoadSeedsFS(fstest.MapFS{
+		"roles.sql":     {Data: []byte("-- migrate:env prod\nSELECT 1;")},
+		"countries.sql": {Data: []byte("SELECT 1;")},
+		"README.md":     {Data: []byte("# seeds")},
+	})
+	if err != nil {
+		t.Fatalf("LoadSeedsFS failed: %v", err)
+	}
+	if len(seeds) != 2 || seeds[0].Name != "countries" || seeds[1].Name != "roles" {
+		t.Fatalf("unexpected seeds: %+v", seeds)
+	}
+	if len(seeds[1].Envs) != 1 || seeds[1].Envs[0] != "prod" || !seeds[1].allowed("prod") || seeds[1].allowed("dev") {
+		t.Errorf("unexpected envs: %+v", seeds[1].Envs)
+	}
+
+	if _, err := LoadSeedsFS(fstest.MapFS{"bad.sql": {Data: []byte("-- migrate:env\nSELECT 1;")}}); err == nil {
+		t.Error("expected error for empty migrate:env")
+	}
+}
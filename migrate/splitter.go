@@ -0,0 +1,323 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect indica las reglas léxicas de SQL que usa el splitter
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite3"
+	DialectGeneric  Dialect = "generic"
+)
+
+// DialectFor devuelve el dialecto correspondiente a un nombre de driver de
+// database/sql
+func DialectFor(driver string) Dialect {
+	switch driver {
+	case "postgres", "pgx":
+		return DialectPostgres
+	case "mysql":
+		return DialectMySQL
+	case "sqlite3", "sqlite":
+		return DialectSQLite
+	default:
+		return DialectGeneric
+	}
+}
+
+// Statement es una sentencia de un script SQL
+type Statement struct {
+	SQL string
+	// Line es la línea del script (desde 1) donde empieza la sentencia
+	Line int
+}
+
+// StatementError indica qué sentencia de un script falló
+type StatementError struct {
+	// Index es el número de sentencia dentro del script, desde 1
+	Index int
+	Line  int
+	Err   error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("sentencia %d (línea %d): %v", e.Index, e.Line, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// SplitStatements divide un script en sentencias. Entiende strings,
+// identificadores entre comillas, comentarios, bloques BEGIN...END (triggers
+// y procedimientos), dollar-quoting de PostgreSQL y la directiva DELIMITER de
+// MySQL. Los comentarios entre sentencias se descartan.
+func SplitStatements(script string, dialect Dialect) ([]Statement, error) {
+	s := &splitter{src: script, dialect: dialect, delim: ";", start: -1}
+	if err := s.split(); err != nil {
+		return nil, err
+	}
+	return s.out, nil
+}
+
+type splitter struct {
+	src     string
+	dialect Dialect
+	delim   string
+	pos     int
+	// start es el offset donde empieza la sentencia actual, -1 si todavía no empezó
+	start int
+	// depth cuenta los bloques BEGIN/CASE abiertos; dentro de un bloque el ";"
+	// no termina la sentencia
+	depth int
+	out   []Statement
+}
+
+// Palabras que después de BEGIN indican el inicio de una transacción y no de
+// un bloque
+var beginTransaction = map[string]bool{
+	"TRANSACTION": true, "WORK": true, "DEFERRED": true, "IMMEDIATE": true,
+	"EXCLUSIVE": true, "ISOLATION": true, "READ": true,
+}
+
+// Palabras que después de END cierran una estructura de control que no se
+// cuenta como bloque (END IF, END LOOP, ...)
+var endControl = map[string]bool{
+	"IF": true, "LOOP": true, "WHILE": true, "REPEAT": true,
+}
+
+func (s *splitter) split() error {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+
+		if s.dialect == DialectMySQL && s.start == -1 && s.atLineStart() && s.hasWordFold("DELIMITER") {
+			if err := s.delimiter(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch {
+		case isSpace(c):
+			s.pos++
+		case strings.HasPrefix(s.src[s.pos:], "--"), s.dialect == DialectMySQL && c == '#':
+			s.skipLine()
+		case strings.HasPrefix(s.src[s.pos:], "/*"):
+			end := strings.Index(s.src[s.pos+2:], "*/")
+			if end < 0 {
+				return fmt.Errorf("línea %d: comentario sin cerrar", s.lineAt(s.pos))
+			}
+			s.pos += end + 4
+		case (s.depth == 0 || s.delim != ";") && strings.HasPrefix(s.src[s.pos:], s.delim):
+			s.emit()
+			s.pos += len(s.delim)
+		default:
+			if s.start == -1 {
+				s.start = s.pos
+			}
+			if err := s.token(c); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.emit()
+	return nil
+}
+
+// token consume un token que forma parte de una sentencia
+func (s *splitter) token(c byte) error {
+	switch {
+	case c == '\'' || c == '"' || (c == '`' && s.dialect != DialectPostgres):
+		return s.skipQuoted(c, s.dialect == DialectMySQL && c != '`')
+	case c == '[' && s.dialect == DialectSQLite:
+		return s.skipQuoted(']', false)
+	case (c == 'E' || c == 'e') && s.dialect == DialectPostgres && strings.HasPrefix(s.src[s.pos+1:], "'"):
+		// E'...': string de Postgres con escapes de barra invertida
+		s.pos++
+		return s.skipQuoted('\'', true)
+	case c == '$' && s.dialect == DialectPostgres:
+		if tag := s.dollarTag(); tag != "" {
+			end := strings.Index(s.src[s.pos+len(tag):], tag)
+			if end < 0 {
+				return fmt.Errorf("línea %d: bloque %s sin cerrar", s.lineAt(s.pos), tag)
+			}
+			s.pos += len(tag) + end + len(tag)
+			return nil
+		}
+		s.pos++
+	case isWordStart(c):
+		s.word()
+	default:
+		s.pos++
+	}
+	return nil
+}
+
+// word consume una palabra y actualiza la profundidad de bloques
+func (s *splitter) word() {
+	w := strings.ToUpper(s.readWord())
+
+	switch w {
+	case "BEGIN":
+		next := s.peekWord()
+		if next != "" && !beginTransaction[next] {
+			s.depth++
+		}
+	case "CASE":
+		s.depth++
+	case "END":
+		next := s.peekWord()
+		if !endControl[next] && s.depth > 0 {
+			s.depth--
+		}
+		// END CASE cierra el CASE de MySQL: ese CASE no abre otro bloque
+		if next == "CASE" {
+			for isSpace(s.src[s.pos]) {
+				s.pos++
+			}
+			s.readWord()
+		}
+	}
+}
+
+func (s *splitter) readWord() string {
+	start := s.pos
+	for s.pos < len(s.src) && isWordChar(s.src[s.pos]) {
+		s.pos++
+	}
+	return s.src[start:s.pos]
+}
+
+// peekWord devuelve en mayúsculas la próxima palabra sin consumirla, o "" si
+// lo siguiente no es una palabra (por ejemplo un ";")
+func (s *splitter) peekWord() string {
+	i := s.pos
+	for i < len(s.src) && isSpace(s.src[i]) {
+		i++
+	}
+	j := i
+	for j < len(s.src) && isWordChar(s.src[j]) {
+		j++
+	}
+	return strings.ToUpper(s.src[i:j])
+}
+
+// skipQuoted consume un literal o identificador entre comillas. Las comillas
+// duplicadas son un escape; con backslash también lo es la barra invertida
+// (strings de MySQL y E'...' de Postgres).
+func (s *splitter) skipQuoted(closing byte, backslash bool) error {
+	open := s.pos
+	s.pos++
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\\' && backslash:
+			s.pos += 2
+		case c == closing && s.pos+1 < len(s.src) && s.src[s.pos+1] == closing:
+			s.pos += 2
+		case c == closing:
+			s.pos++
+			return nil
+		default:
+			s.pos++
+		}
+	}
+	return fmt.Errorf("línea %d: %c sin cerrar", s.lineAt(open), s.src[open])
+}
+
+// dollarTag devuelve el tag ($$ o $nombre$) que empieza en la posición actual
+func (s *splitter) dollarTag() string {
+	i := s.pos + 1
+	for i < len(s.src) && isWordChar(s.src[i]) {
+		i++
+	}
+	if i < len(s.src) && s.src[i] == '$' {
+		tag := s.src[s.pos : i+1]
+		// $1, $2: parámetros, no dollar-quoting
+		if len(tag) > 2 && tag[1] >= '0' && tag[1] <= '9' {
+			return ""
+		}
+		return tag
+	}
+	return ""
+}
+
+// delimiter procesa una línea "DELIMITER <nuevo>"
+func (s *splitter) delimiter() error {
+	line := s.pos
+	end := strings.IndexByte(s.src[s.pos:], '\n')
+	if end < 0 {
+		end = len(s.src) - s.pos
+	}
+	fields := strings.Fields(s.src[s.pos : s.pos+end])
+	if len(fields) != 2 {
+		return fmt.Errorf("línea %d: DELIMITER necesita un único delimitador", s.lineAt(line))
+	}
+	s.delim = fields[1]
+	s.pos += end
+	return nil
+}
+
+func (s *splitter) skipLine() {
+	end := strings.IndexByte(s.src[s.pos:], '\n')
+	if end < 0 {
+		s.pos = len(s.src)
+		return
+	}
+	s.pos += end
+}
+
+// emit cierra la sentencia actual, si hay una
+func (s *splitter) emit() {
+	if s.start == -1 {
+		return
+	}
+	if stmt := strings.TrimSpace(s.src[s.start:s.pos]); stmt != "" {
+		s.out = append(s.out, Statement{SQL: stmt, Line: s.lineAt(s.start)})
+	}
+	s.start = -1
+	s.depth = 0
+}
+
+func (s *splitter) atLineStart() bool {
+	for i := s.pos - 1; i >= 0; i-- {
+		switch s.src[i] {
+		case '\n':
+			return true
+		case ' ', '\t', '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (s *splitter) hasWordFold(word string) bool {
+	end := s.pos + len(word)
+	if end > len(s.src) || !strings.EqualFold(s.src[s.pos:end], word) {
+		return false
+	}
+	return end == len(s.src) || !isWordChar(s.src[end])
+}
+
+func (s *splitter) lineAt(offset int) int {
+	return strings.Count(s.src[:offset], "\n") + 1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWordChar(c byte) bool {
+	return isWordStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,209 @@
+package migrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		name     string
+		dialect  Dialect
+		script   string
+		expected []string
+	}{
+		{
+			name:     "simple statements",
+			dialect:  DialectGeneric,
+			script:   "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			expected: []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:     "last statement without delimiter",
+			dialect:  DialectGeneric,
+			script:   "SELECT 1;\nSELECT 2",
+			expected: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:     "delimiters inside strings and identifiers",
+			dialect:  DialectGeneric,
+			script:   `INSERT INTO "weird;table" VALUES ('a;b', 'it''s;');SELECT 2;`,
+			expected: []string{`INSERT INTO "weird;table" VALUES ('a;b', 'it''s;')`, "SELECT 2"},
+		},
+		{
+			name:     "comments are skipped between statements",
+			dialect:  DialectGeneric,
+			script:   "-- header; with delimiter\nSELECT 1; /* block; comment */\n-- trailing\n",
+			expected: []string{"SELECT 1"},
+		},
+		{
+			name:     "comments inside a statement are kept",
+			dialect:  DialectGeneric,
+			script:   "SELECT 1 -- uno;\n, 2;",
+			expected: []string{"SELECT 1 -- uno;\n, 2"},
+		},
+		{
+			name:    "postgres dollar quoting",
+			dialect: DialectPostgres,
+			script: `CREATE FUNCTION f() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+DO $body$ BEGIN PERFORM 1; END $body$;`,
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.updated_at = now();\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql",
+				"DO $body$ BEGIN PERFORM 1; END $body$",
+			},
+		},
+		{
+			name:     "postgres positional parameters are not dollar quotes",
+			dialect:  DialectPostgres,
+			script:   "PREPARE p AS SELECT $1;SELECT 2;",
+			expected: []string{"PREPARE p AS SELECT $1", "SELECT 2"},
+		},
+		{
+			name:     "postgres escape strings",
+			dialect:  DialectPostgres,
+			script:   `SELECT E'it\'s; ok'; SELECT e'\\'; SELECT 'a\';`,
+			expected: []string{`SELECT E'it\'s; ok'`, `SELECT e'\\'`, `SELECT 'a\'`},
+		},
+		{
+			name:    "sqlite trigger with begin end",
+			dialect: DialectSQLite,
+			script: `CREATE TRIGGER t AFTER INSERT ON users BEGIN
+  UPDATE stats SET total = total + 1;
+  UPDATE stats SET kind = CASE WHEN NEW.admin THEN 'a' ELSE 'u' END;
+END;
+SELECT 1;`,
+			expected: []string{
+				"CREATE TRIGGER t AFTER INSERT ON users BEGIN\n  UPDATE stats SET total = total + 1;\n  UPDATE stats SET kind = CASE WHEN NEW.admin THEN 'a' ELSE 'u' END;\nEND",
+				"SELECT 1",
+			},
+		},
+		{
+			name:     "begin transaction is not a block",
+			dialect:  DialectSQLite,
+			script:   "BEGIN TRANSACTION;\nSELECT 1;\nCOMMIT;",
+			expected: []string{"BEGIN TRANSACTION", "SELECT 1", "COMMIT"},
+		},
+		{
+			name:    "mysql procedure with control flow",
+			dialect: DialectMySQL,
+			script: `CREATE PROCEDURE p()
+BEGIN
+  IF 1 THEN
+    SELECT 'a';
+  END IF;
+  WHILE 0 DO SELECT 1; END WHILE;
+END;
+SELECT 2;`,
+			expected: []string{
+				"CREATE PROCEDURE p()\nBEGIN\n  IF 1 THEN\n    SELECT 'a';\n  END IF;\n  WHILE 0 DO SELECT 1; END WHILE;\nEND",
+				"SELECT 2",
+			},
+		},
+		{
+			name:    "mysql end case without delimiter",
+			dialect: DialectMySQL,
+			script: `CREATE PROCEDURE p(x INT)
+BEGIN
+  CASE x WHEN 1 THEN SELECT 'a'; ELSE SELECT 'b'; END CASE;
+END;
+CREATE TABLE t (id INT);`,
+			expected: []string{
+				"CREATE PROCEDURE p(x INT)\nBEGIN\n  CASE x WHEN 1 THEN SELECT 'a'; ELSE SELECT 'b'; END CASE;\nEND",
+				"CREATE TABLE t (id INT)",
+			},
+		},
+		{
+			name:    "mysql delimiter directive",
+			dialect: DialectMySQL,
+			script: `DELIMITER //
+CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END//
+DELIMITER ;
+SELECT 3;`,
+			expected: []string{"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END", "SELECT 3"},
+		},
+		{
+			name:     "mysql backslash escapes and hash comments",
+			dialect:  DialectMySQL,
+			script:   "# comment;\nINSERT INTO t VALUES ('a\\';b', `col;name`);",
+			expected: []string{"INSERT INTO t VALUES ('a\\';b', `col;name`)"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			statements, err := SplitStatements(tc.script, tc.dialect)
+			if err != nil {
+				t.Fatalf("SplitStatements failed: %v", err)
+			}
+
+			if len(statements) != len(tc.expected) {
+				t.Fatalf("expected %d statements, got %d: %q", len(tc.expected), len(statements), statements)
+			}
+			for i, stmt := range statements {
+				if stmt.SQL != tc.expected[i] {
+					t.Errorf("statement %d:\nexpected %q\ngot      %q", i, tc.expected[i], stmt.SQL)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitStatementsLines(t *testing.T) {
+	script := "-- header\n\nSELECT 1;\n\nSELECT\n  2;"
+
+	statements, err := SplitStatements(script, DialectGeneric)
+	if err != nil {
+		t.Fatalf("SplitStatements failed: %v", err)
+	}
+
+	if statements[0].Line != 3 || statements[1].Line != 5 {
+		t.Errorf("unexpected lines: %d, %d", statements[0].Line, statements[1].Line)
+	}
+}
+
+func TestSplitStatementsErrors(t *testing.T) {
+	cases := map[string]struct {
+		dialect Dialect
+		script  string
+	}{
+		"unterminated string":       {DialectGeneric, "SELECT 'abc;"},
+		"unterminated comment":      {DialectGeneric, "SELECT 1; /* never closed"},
+		"unterminated dollar quote": {DialectPostgres, "DO $$ BEGIN END;"},
+		"empty delimiter":           {DialectMySQL, "DELIMITER\nSELECT 1;"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := SplitStatements(tc.script, tc.dialect); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestStatementErrorReportsPosition(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+
+	dir := t.TempDir()
+	CreateMigrationFile(t, dir, "1_test.up.sql", "CREATE TABLE a (id INTEGER);\n\nCREATE TABLE b (id INTEGER);\nINVALID SQL HERE;\n")
+
+	err := Up(db, dir, false)
+
+	var stmtErr *StatementError
+	if !errors.As(err, &stmtErr) {
+		t.Fatalf("expected *StatementError, got %v", err)
+	}
+	if stmtErr.Index != 3 || stmtErr.Line != 4 {
+		t.Errorf("expected statement 3 at line 4, got %d at line %d", stmtErr.Index, stmtErr.Line)
+	}
+	if !strings.Contains(err.Error(), "sentencia 3 (línea 4)") {
+		t.Errorf("error message should include position: %v", err)
+	}
+}
@@ -1,23 +1,255 @@
 package migrate
 
-import "github.com/jmoiron/sqlx"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
-func ensure(db *sqlx.DB) error {
-	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	"github.com/jmoiron/sqlx"
+)
+
+func ensure(ctx context.Context, db *sqlx.DB, table string) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (
 		version BIGINT PRIMARY KEY,
-		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		dirty BOOLEAN NOT NULL DEFAULT FALSE,
+		checksum VARCHAR(64),
+		out_of_order BOOLEAN NOT NULL DEFAULT FALSE
 	)`)
+	if err != nil {
+		return err
+	}
+
+	// Tablas creadas por versiones anteriores no tienen las columnas nuevas
+	if err := ensureColumn(ctx, db, table, "dirty", "BOOLEAN NOT NULL DEFAULT FALSE"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, table, "checksum", "VARCHAR(64)"); err != nil {
+		return err
+	}
+	return ensureColumn(ctx, db, table, "out_of_order", "BOOLEAN NOT NULL DEFAULT FALSE")
+}
+
+// ensureColumn agrega la columna a la tabla si todavía no existe
+func ensureColumn(ctx context.Context, db *sqlx.DB, table, column, definition string) error {
+	rows, err := db.QueryContext(ctx, `SELECT `+column+` FROM `+table+` WHERE 1 = 0`)
+	if err == nil {
+		return rows.Close()
+	}
+
+	_, err = db.ExecContext(ctx, `ALTER TABLE `+table+` ADD COLUMN `+column+` `+definition)
 	return err
 }
 
-func applied(db *sqlx.DB) ([]int, error) {
+// applied devuelve las versiones aplicadas por completo, sin las dirty
+func applied(ctx context.Context, db *sqlx.DB, table string) ([]int, error) {
 	var versions []int
-	err := db.Select(&versions, `SELECT version FROM schema_migrations ORDER BY version`)
+	err := db.SelectContext(ctx, &versions, `SELECT version FROM `+table+` WHERE dirty = FALSE ORDER BY version`)
 	return versions, err
 }
 
-func last(db *sqlx.DB) (int, error) {
+// appliedRow es una fila de la tabla de control
+type appliedRow struct {
+	Version   int       `db:"version"`
+	AppliedAt timestamp `db:"applied_at"`
+	Dirty     bool      `db:"dirty"`
+	// OutOfOrder indica que se aplicó después de una versión mayor
+	OutOfOrder bool `db:"out_of_order"`
+}
+
+// appliedRows devuelve todas las filas de la tabla de control, incluidas las dirty
+func appliedRows(ctx context.Context, db *sqlx.DB, table string) ([]appliedRow, error) {
+	var rows []appliedRow
+	err := db.SelectContext(ctx, &rows, `SELECT version, applied_at, dirty, out_of_order FROM `+table+` ORDER BY version`)
+	return rows, err
+}
+
+// timestamp lee una columna TIMESTAMP aunque el driver la devuelva como texto,
+// como MySQL sin parseTime=true
+type timestamp struct {
+	Time time.Time
+}
+
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05"}
+
+func (t *timestamp) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return fmt.Errorf("tipo de fecha no soportado: %T", src)
+	}
+
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("fecha inválida: %q", text)
+}
+
+// ensureRepeatable crea la tabla de control de las migraciones repetibles o
+// de los seeds
+func ensureRepeatable(ctx context.Context, db *sqlx.DB, table string) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (
+		name VARCHAR(255) PRIMARY KEY,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// repeatableRow es una fila de la tabla de migraciones repetibles
+type repeatableRow struct {
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt timestamp `db:"applied_at"`
+}
+
+// repeatableRows devuelve las migraciones repetibles aplicadas por nombre
+func repeatableRows(ctx context.Context, db *sqlx.DB, table string) (map[string]repeatableRow, error) {
+	var rows []repeatableRow
+	if err := db.SelectContext(ctx, &rows, `SELECT name, checksum, applied_at FROM `+table); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]repeatableRow, len(rows))
+	for _, r := range rows {
+		out[r.Name] = r
+	}
+	return out, nil
+}
+
+// readRepeatableRows es repeatableRows para los caminos de solo lectura
+// (status y dry-run): si la tabla todavía no existe no la crea, no hay nada
+// aplicado
+func readRepeatableRows(ctx context.Context, db *sqlx.DB, table string) (map[string]repeatableRow, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM `+table+` WHERE 1 = 0`)
+	if err != nil {
+		return map[string]repeatableRow{}, nil
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return repeatableRows(ctx, db, table)
+}
+
+// recordRepeatable guarda el checksum con el que se aplicó la migración repetible
+func recordRepeatable(ctx context.Context, tx *sqlx.Tx, table string, r Repeatable) error {
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM `+table+` WHERE name = ?`), r.Name); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO `+table+`(name, checksum) VALUES(?, ?)`), r.Name, r.Checksum())
+	return err
+}
+
+// dirtyVersion devuelve la versión marcada como dirty, si hay alguna
+func dirtyVersion(ctx context.Context, db *sqlx.DB, table string) (int, bool, error) {
 	var v int
-	err := db.Get(&v, `SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1`)
-	return v, err
+	err := db.GetContext(ctx, &v, `SELECT version FROM `+table+` WHERE dirty = TRUE ORDER BY version LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return v, true, nil
+}
+
+// markDirty registra que la migración empezó a ejecutarse. Se escribe fuera
+// de la transacción de la migración para que sobreviva a un fallo. Al
+// aplicarla también guarda si se aplica fuera de orden.
+func markDirty(ctx context.Context, db *sqlx.DB, table string, version int, dir Direction, outOfOrder bool) error {
+	if dir == DirectionUp {
+		_, err := db.ExecContext(ctx, db.Rebind(`INSERT INTO `+table+`(version, dirty, out_of_order) VALUES(?, TRUE, ?)`), version, outOfOrder)
+		return err
+	}
+	_, err := db.ExecContext(ctx, db.Rebind(`UPDATE `+table+` SET dirty = TRUE WHERE version = ?`), version)
+	return err
+}
+
+// clearDirty deshace markDirty cuando la migración no llegó a cambiar nada
+func clearDirty(ctx context.Context, db *sqlx.DB, table string, version int, dir Direction) error {
+	query := `UPDATE ` + table + ` SET dirty = FALSE WHERE version = ?`
+	if dir == DirectionUp {
+		query = `DELETE FROM ` + table + ` WHERE version = ?`
+	}
+	_, err := db.ExecContext(ctx, db.Rebind(query), version)
+	return err
+}
+
+// record marca la migración como aplicada, guarda su checksum y limpia su
+// marca dirty
+func record(ctx context.Context, tx *sqlx.Tx, table string, mig Migration) error {
+	_, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE `+table+` SET dirty = FALSE, checksum = ?, applied_at = CURRENT_TIMESTAMP WHERE version = ?`),
+		mig.Checksum(), mig.Version)
+	return err
+}
+
+// checksums devuelve el checksum guardado de cada versión aplicada. Las
+// aplicadas antes de que existiera la columna tienen checksum vacío.
+func checksums(ctx context.Context, db *sqlx.DB, table string) (map[int]string, error) {
+	var rows []struct {
+		Version  int            `db:"version"`
+		Checksum sql.NullString `db:"checksum"`
+	}
+	if err := db.SelectContext(ctx, &rows, `SELECT version, checksum FROM `+table+` WHERE dirty = FALSE`); err != nil {
+		return nil, err
+	}
+
+	out := make(map[int]string, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r.Checksum.String
+	}
+	return out, nil
+}
+
+func updateChecksum(ctx context.Context, db *sqlx.DB, table string, version int, checksum string) error {
+	_, err := db.ExecContext(ctx, db.Rebind(`UPDATE `+table+` SET checksum = ? WHERE version = ?`), checksum, version)
+	return err
+}
+
+func unrecord(ctx context.Context, tx *sqlx.Tx, table string, version int) error {
+	_, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM `+table+` WHERE version = ?`), version)
+	return err
+}
+
+// forceVersion deja version como última migración aplicada y limpia las
+// marcas dirty. Las versiones posteriores quedan como no aplicadas.
+func forceVersion(ctx context.Context, db *sqlx.DB, table string, version int) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM `+table+` WHERE dirty = TRUE OR version > ?`), version); err != nil {
+		return err
+	}
+
+	if version > 0 {
+		var count int
+		if err := tx.GetContext(ctx, &count, tx.Rebind(`SELECT COUNT(*) FROM `+table+` WHERE version = ?`), version); err != nil {
+			return err
+		}
+		if count == 0 {
+			if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO `+table+`(version) VALUES(?)`), version); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
 }
@@ -1,7 +1,143 @@
 package migrate
 
-import "github.com/jmoiron/sqlx"
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
 
+	"github.com/jmoiron/sqlx"
+)
+
+// Status devuelve las versiones aplicadas en la tabla de control
 func Status(db *sqlx.DB) ([]int, error) {
-	return applied(db)
+	m, err := New(db, Options{})
+	if err != nil {
+		return nil, err
+	}
+	return m.Status(context.Background())
+}
+
+// Status devuelve las versiones aplicadas, en orden ascendente
+func (m *Migrator) Status(ctx context.Context) ([]int, error) {
+	return applied(ctx, m.db, m.table)
+}
+
+// MigrationState es el estado de una migración en un StatusReport
+type MigrationState string
+
+const (
+	// StateApplied es una migración aplicada cuyo archivo existe
+	StateApplied MigrationState = "applied"
+	// StatePending es una migración sin aplicar posterior a la última aplicada
+	StatePending MigrationState = "pending"
+	// StateMissing es una migración sin aplicar anterior a la última aplicada,
+	// por ejemplo una rama que se mergeó tarde: up la aplicaría fuera de orden
+	StateMissing MigrationState = "missing"
+	// StateOrphaned es una versión aplicada cuyo archivo ya no existe
+	StateOrphaned MigrationState = "orphaned"
+	// StateDirty es una migración que falló a mitad de camino
+	StateDirty MigrationState = "dirty"
+)
+
+// MigrationStatus es una fila del StatusReport
+type MigrationStatus struct {
+	Version int
+	// Name está vacío en las migraciones huérfanas
+	Name  string
+	State MigrationState
+	// AppliedAt es cero si la migración no está aplicada
+	AppliedAt time.Time
+	// HasDown indica si la migración se puede revertir
+	HasDown bool
+	// OutOfOrder indica que se aplicó después de una versión mayor
+	OutOfOrder bool
+}
+
+// StatusReport cruza las migraciones disponibles con la tabla de control
+type StatusReport struct {
+	// Migrations está ordenado por versión
+	Migrations []MigrationStatus
+	// Current es la última versión aplicada, 0 si no hay ninguna
+	Current int
+	// Repeatables son las migraciones repetibles, ordenadas por nombre
+	Repeatables []RepeatableStatus
+}
+
+// Count devuelve cuántas migraciones del reporte están en el estado dado
+func (r *StatusReport) Count(state MigrationState) int {
+	n := 0
+	for _, s := range r.Migrations {
+		if s.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+// StatusReport devuelve el estado de cada migración: las de Options.Source y
+// las registradas en Go, más las aplicadas cuyo archivo ya no existe
+func (m *Migrator) StatusReport(ctx context.Context) (*StatusReport, error) {
+	if err := ensure(ctx, m.db, m.table); err != nil {
+		return nil, err
+	}
+
+	migrations, err := m.load()
+	if err != nil {
+		return nil, fmt.Errorf("error cargando migraciones: %w", err)
+	}
+
+	rows, err := appliedRows(ctx, m.db, m.table)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo migraciones aplicadas: %w", err)
+	}
+
+	report := &StatusReport{}
+	byVersion := make(map[int]appliedRow, len(rows))
+	for _, row := range rows {
+		byVersion[row.Version] = row
+		if !row.Dirty && row.Version > report.Current {
+			report.Current = row.Version
+		}
+	}
+
+	for _, mig := range migrations {
+		status := MigrationStatus{Version: mig.Version, Name: mig.Name, HasDown: mig.hasDown()}
+
+		row, ok := byVersion[mig.Version]
+		switch {
+		case !ok && mig.Version < report.Current:
+			status.State = StateMissing
+		case !ok:
+			status.State = StatePending
+		case row.Dirty:
+			status.State = StateDirty
+		default:
+			status.State = StateApplied
+			status.AppliedAt = row.AppliedAt.Time
+			status.OutOfOrder = row.OutOfOrder
+		}
+		delete(byVersion, mig.Version)
+
+		report.Migrations = append(report.Migrations, status)
+	}
+
+	// Lo que queda en la tabla de control no tiene archivo
+	for _, row := range byVersion {
+		status := MigrationStatus{Version: row.Version, State: StateOrphaned, AppliedAt: row.AppliedAt.Time}
+		if row.Dirty {
+			status.State = StateDirty
+		}
+		report.Migrations = append(report.Migrations, status)
+	}
+
+	sort.Slice(report.Migrations, func(i, j int) bool {
+		return report.Migrations[i].Version < report.Migrations[j].Version
+	})
+
+	report.Repeatables, err = m.repeatableStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
 }
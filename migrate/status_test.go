@@ -0,0 +1,91 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStatusReport(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	applied, _ := New(db, Options{Source: fstest.MapFS{
+		"1_users.up.sql":       {Data: []byte("CREATE TABLE users (id INTEGER);")},
+		"1_users.down.sql":     {Data: []byte("DROP TABLE users;")},
+		"3_posts.up.sql":       {Data: []byte("CREATE TABLE posts (id INTEGER);")},
+		"4_old_feature.up.sql": {Data: []byte("CREATE TABLE old (id INTEGER);")},
+	}})
+	if _, err := applied.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	db.MustExec(`INSERT INTO schema_migrations (version, dirty) VALUES (6, TRUE)`)
+
+	// El archivo de la 4 se borró y aparecieron la 2 (vieja) y la 5
+	m, _ := New(db, Options{Source: fstest.MapFS{
+		"1_users.up.sql":    {Data: []byte("CREATE TABLE users (id INTEGER);")},
+		"1_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+		"2_emails.up.sql":   {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"3_posts.up.sql":    {Data: []byte("CREATE TABLE posts (id INTEGER);")},
+		"5_comments.up.sql": {Data: []byte("CREATE TABLE comments (id INTEGER);")},
+	}})
+
+	report, err := m.StatusReport(ctx)
+	if err != nil {
+		t.Fatalf("StatusReport failed: %v", err)
+	}
+
+	expected := []struct {
+		version int
+		name    string
+		state   MigrationState
+		hasDown bool
+	}{
+		{1, "users", StateApplied, true},
+		{2, "emails", StateMissing, false},
+		{3, "posts", StateApplied, false},
+		{4, "", StateOrphaned, false},
+		{5, "comments", StatePending, false},
+		{6, "", StateDirty, false},
+	}
+
+	if len(report.Migrations) != len(expected) {
+		t.Fatalf("expected %d rows, got %+v", len(expected), report.Migrations)
+	}
+	for i, e := range expected {
+		got := report.Migrations[i]
+		if got.Version != e.version || got.Name != e.name || got.State != e.state || got.HasDown != e.hasDown {
+			t.Errorf("row %d: expected %+v, got %+v", i, e, got)
+		}
+	}
+
+	if report.Current != 4 {
+		t.Errorf("expected current version 4, got %d", report.Current)
+	}
+	if report.Migrations[0].AppliedAt.IsZero() || !report.Migrations[1].AppliedAt.IsZero() {
+		t.Error("applied_at should be set only for applied migrations")
+	}
+	if report.Count(StatePending) != 1 || report.Count(StateApplied) != 2 {
+		t.Errorf("unexpected counts in %+v", report.Migrations)
+	}
+}
+
+func TestTimestampScan(t *testing.T) {
+	cases := map[string]any{
+		"text":  "2026-10-16 08:30:00",
+		"bytes": []byte("2026-10-16T08:30:00Z"),
+	}
+
+	for name, src := range cases {
+		t.Run(name, func(t *testing.T) {
+			var ts timestamp
+			if err := ts.Scan(src); err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+			if ts.Time.Hour() != 8 || ts.Time.Minute() != 30 {
+				t.Errorf("unexpected time: %s", ts.Time)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// DefaultLockTable es la tabla que usa TableLocker si Table está vacío
+	DefaultLockTable = "migrator_locks"
+	// DefaultLockTTL es cuánto dura el lock de TableLocker sin renovarse
+	DefaultLockTTL = 30 * time.Second
+)
+
+// TableLocker es un Locker portable basado en una fila por clave en una tabla
+// de locks. La fila tiene un dueño y un vencimiento que se renueva mientras el
+// lock está tomado; si el proceso muere, otro puede tomarlo cuando vence.
+// Sirve para cualquier driver de database/sql.
+//
+// Los vencimientos se calculan con el reloj de cada proceso, así que TTL tiene
+// que ser bastante mayor que la diferencia de reloj entre hosts.
+type TableLocker struct {
+	// Table es la tabla de locks. Se puede cambiar antes de llamar a Lock.
+	Table string
+	// TTL es la duración del lock sin renovar. Se renueva cada TTL/3.
+	TTL time.Duration
+
+	db     *sqlx.DB
+	key    string
+	owner  string
+	locked bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewTableLocker crea un locker sobre la fila key de DefaultLockTable
+func NewTableLocker(db *sqlx.DB, key string) *TableLocker {
+	return &TableLocker{Table: DefaultLockTable, TTL: DefaultLockTTL, db: db, key: key}
+}
+
+func (l *TableLocker) Lock(ctx context.Context) error {
+	if l.locked {
+		return errors.New("ya está bloqueado")
+	}
+	if !identifier.MatchString(l.Table) {
+		return fmt.Errorf("nombre de tabla de lock inválido: %q", l.Table)
+	}
+	if l.TTL <= 0 {
+		l.TTL = DefaultLockTTL
+	}
+
+	if err := l.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	owner, err := newOwnerID()
+	if err != nil {
+		return fmt.Errorf("error generando dueño del lock: %w", err)
+	}
+	host, _ := os.Hostname()
+
+	err = waitLock(ctx, func(ctx context.Context) (bool, error) {
+		return l.tryLock(ctx, owner, host)
+	})
+	if err != nil {
+		return err
+	}
+
+	l.owner = owner
+	l.locked = true
+	l.stop = make(chan struct{})
+	l.wg.Add(1)
+	go l.heartbeat()
+	return nil
+}
+
+// tryLock borra la fila si venció e intenta insertar la propia. La clave
+// primaria garantiza que solo un INSERT gane.
+func (l *TableLocker) tryLock(ctx context.Context, owner, host string) (bool, error) {
+	now := time.Now()
+
+	_, err := l.db.ExecContext(ctx, l.db.Rebind(`DELETE FROM `+l.Table+` WHERE lock_key = ? AND expires_at < ?`),
+		l.key, now.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("error limpiando lock vencido: %w", err)
+	}
+
+	_, err = l.db.ExecContext(ctx, l.db.Rebind(`INSERT INTO `+l.Table+` (lock_key, owner, host, pid, acquired_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`),
+		l.key, owner, host, os.Getpid(), now.UnixMilli(), now.Add(l.TTL).UnixMilli())
+	if err == nil {
+		return true, nil
+	}
+
+	// El error de clave duplicada es distinto en cada driver: si la fila
+	// existe, el lock lo tiene otro
+	var count int
+	if cerr := l.db.GetContext(ctx, &count, l.db.Rebind(`SELECT COUNT(*) FROM `+l.Table+` WHERE lock_key = ?`), l.key); cerr == nil && count > 0 {
+		return false, nil
+	}
+	return false, fmt.Errorf("error adquiriendo lock: %w", err)
+}
+
+// heartbeat renueva el vencimiento del lock hasta Unlock
+func (l *TableLocker) heartbeat() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.TTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			// Un error puntual no pierde el lock: se reintenta en el próximo tick
+			ctx, cancel := context.WithTimeout(context.Background(), l.TTL/3)
+			l.renew(ctx)
+			cancel()
+		}
+	}
+}
+
+// renew extiende el vencimiento si el lock sigue siendo propio
+func (l *TableLocker) renew(ctx context.Context) (bool, error) {
+	result, err := l.db.ExecContext(ctx, l.db.Rebind(`UPDATE `+l.Table+` SET expires_at = ? WHERE lock_key = ? AND owner = ?`),
+		time.Now().Add(l.TTL).UnixMilli(), l.key, l.owner)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+func (l *TableLocker) Unlock() error {
+	if !l.locked {
+		return nil
+	}
+
+	close(l.stop)
+	l.wg.Wait()
+
+	_, err := l.db.Exec(l.db.Rebind(`DELETE FROM `+l.Table+` WHERE lock_key = ? AND owner = ?`), l.key, l.owner)
+	if err != nil {
+		return fmt.Errorf("error liberando lock: %w", err)
+	}
+
+	l.locked = false
+	return nil
+}
+
+// Held comprueba que la fila siga siendo de este locker y no haya vencido
+func (l *TableLocker) Held(ctx context.Context) (bool, error) {
+	if !l.locked {
+		return false, nil
+	}
+
+	var count int
+	err := l.db.GetContext(ctx, &count, l.db.Rebind(`SELECT COUNT(*) FROM `+l.Table+` WHERE lock_key = ? AND owner = ? AND expires_at >= ?`),
+		l.key, l.owner, time.Now().UnixMilli())
+	return count == 1, err
+}
+
+func (l *TableLocker) Inspect(ctx context.Context) (LockInfo, error) {
+	info := LockInfo{Key: l.key}
+	if err := l.ensureTable(ctx); err != nil {
+		return info, err
+	}
+
+	var row struct {
+		Host       sql.NullString `db:"host"`
+		PID        sql.NullInt64  `db:"pid"`
+		AcquiredAt int64          `db:"acquired_at"`
+		ExpiresAt  int64          `db:"expires_at"`
+	}
+	err := l.db.GetContext(ctx, &row, l.db.Rebind(`SELECT host, pid, acquired_at, expires_at FROM `+l.Table+` WHERE lock_key = ?`), l.key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return info, nil
+	}
+	if err != nil {
+		return info, fmt.Errorf("error consultando %s: %w", l.Table, err)
+	}
+
+	// Un lock vencido lo puede tomar cualquiera: se informa como libre
+	if time.Now().UnixMilli() > row.ExpiresAt {
+		return info, nil
+	}
+
+	info.Held = true
+	info.PID = int(row.PID.Int64)
+	info.Host = row.Host.String
+	info.AcquiredAt = time.UnixMilli(row.AcquiredAt)
+	return info, nil
+}
+
+// ForceRelease borra la fila del lock sin importar el dueño
+func (l *TableLocker) ForceRelease(ctx context.Context) error {
+	if err := l.ensureTable(ctx); err != nil {
+		return err
+	}
+	_, err := l.db.ExecContext(ctx, l.db.Rebind(`DELETE FROM `+l.Table+` WHERE lock_key = ?`), l.key)
+	return err
+}
+
+// ensureTable crea la tabla de locks. Los tiempos se guardan como
+// milisegundos Unix para no depender de los tipos de fecha de cada driver.
+func (l *TableLocker) ensureTable(ctx context.Context) error {
+	_, err := l.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+l.Table+` (
+		lock_key VARCHAR(255) PRIMARY KEY,
+		owner VARCHAR(64) NOT NULL,
+		host VARCHAR(255),
+		pid BIGINT,
+		acquired_at BIGINT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("error creando tabla de lock: %w", err)
+	}
+	return nil
+}
+
+// newOwnerID identifica a un Lock: distingue también dos lockers del mismo proceso
+func newOwnerID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,144 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestTableLocker(t *testing.T) {
+	db := SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	t.Run("lock excludes other owners", func(t *testing.T) {
+		holder := NewTableLocker(db, "shop/schema_migrations")
+		if err := holder.Lock(ctx); err != nil {
+			t.Fatalf("Lock failed: %v", err)
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, 150*time.Millisecond)
+		defer cancel()
+		if err := NewTableLocker(db, "shop/schema_migrations").Lock(waitCtx); !errors.Is(err, ErrLocked) {
+			t.Errorf("expected ErrLocked, got %v", err)
+		}
+
+		// Otra clave es otro lock
+		other := NewTableLocker(db, "billing/schema_migrations")
+		if err := other.Lock(ctx); err != nil {
+			t.Errorf("lock with another key failed: %v", err)
+		}
+		other.Unlock()
+
+		if err := holder.Unlock(); err != nil {
+			t.Fatalf("Unlock failed: %v", err)
+		}
+
+		again := NewTableLocker(db, "shop/schema_migrations")
+		if err := again.Lock(ctx); err != nil {
+			t.Errorf("lock after release failed: %v", err)
+		}
+		again.Unlock()
+	})
+
+	t.Run("expired lock can be taken over", func(t *testing.T) {
+		locker := NewTableLocker(db, "expired")
+		if err := locker.ensureTable(ctx); err != nil {
+			t.Fatalf("ensureTable failed: %v", err)
+		}
+
+		// Lock de un proceso que murió sin liberarlo
+		past := time.Now().Add(-time.Minute).UnixMilli()
+		db.MustExec(`INSERT INTO migrator_locks (lock_key, owner, acquired_at, expires_at) VALUES ('expired', 'dead', ?, ?)`, past, past)
+
+		lockCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if err := locker.Lock(lockCtx); err != nil {
+			t.Fatalf("expired lock was not taken over: %v", err)
+		}
+		locker.Unlock()
+	})
+
+	t.Run("heartbeat keeps the lock alive", func(t *testing.T) {
+		holder := NewTableLocker(db, "heartbeat")
+		holder.TTL = 150 * time.Millisecond
+		if err := holder.Lock(ctx); err != nil {
+			t.Fatalf("Lock failed: %v", err)
+		}
+		defer holder.Unlock()
+
+		time.Sleep(400 * time.Millisecond)
+
+		info, err := NewTableLocker(db, "heartbeat").Inspect(ctx)
+		if err != nil {
+			t.Fatalf("Inspect failed: %v", err)
+		}
+		if !info.Held || info.PID != os.Getpid() {
+			t.Errorf("lock should still be held after its TTL: %+v", info)
+		}
+	})
+
+	t.Run("force release", func(t *testing.T) {
+		holder := NewTableLocker(db, "stale")
+		if err := holder.Lock(ctx); err != nil {
+			t.Fatalf("Lock failed: %v", err)
+		}
+		defer holder.Unlock()
+
+		other := NewTableLocker(db, "stale")
+		if err := other.ForceRelease(ctx); err != nil {
+			t.Fatalf("ForceRelease failed: %v", err)
+		}
+		if info, _ := other.Inspect(ctx); info.Held {
+			t.Error("lock should be free after ForceRelease")
+		}
+	})
+}
+
+func TestMigratorLockStrategy(t *testing.T) {
+	t.Run("table strategy on a known driver", func(t *testing.T) {
+		db := SetupTestDB(t)
+		defer db.Close()
+
+		m, err := New(db, Options{Source: os.DirFS(SetupTestMigrations(t)), Lock: LockTable})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if _, err := m.Up(context.Background()); err != nil {
+			t.Fatalf("Up failed: %v", err)
+		}
+
+		if !TableExists(t, db, DefaultLockTable) {
+			t.Error("lock table was not used")
+		}
+		if TableExists(t, db, "migration_lock") {
+			t.Error("native SQLite lock should not be used")
+		}
+	})
+
+	t.Run("unknown driver", func(t *testing.T) {
+		db := SetupTestDB(t)
+		defer db.Close()
+
+		m, _ := New(sqlx.NewDb(db.DB, "custom"), Options{Source: os.DirFS(SetupTestMigrations(t))})
+		if _, err := m.Up(context.Background()); err != nil {
+			t.Fatalf("Up failed with unknown driver: %v", err)
+		}
+		if len(GetAppliedMigrations(t, db)) != 3 {
+			t.Error("migrations were not applied")
+		}
+	})
+
+	t.Run("invalid strategy", func(t *testing.T) {
+		db := SetupTestDB(t)
+		defer db.Close()
+
+		if _, err := New(db, Options{Lock: "zookeeper"}); err == nil {
+			t.Error("expected error with unknown lock strategy")
+		}
+	})
+}
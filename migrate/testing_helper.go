@@ -1,6 +1,7 @@
 package migrate
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,6 +19,9 @@ func SetupTestDB(t *testing.T) *sqlx.DB {
 	if err != nil {
 		t.Fatalf("error creando DB de prueba: %v", err)
 	}
+	// Cada conexión a :memory: abre una base distinta, así que todo el
+	// pool tiene que compartir la misma conexión
+	db.SetMaxOpenConns(1)
 	
 	return db
 }
@@ -105,7 +109,7 @@ func IndexExists(t *testing.T, db *sqlx.DB, indexName string) bool {
 func GetAppliedMigrations(t *testing.T, db *sqlx.DB) []int {
 	t.Helper()
 	
-	versions, err := applied(db)
+	versions, err := applied(context.Background(), db, DefaultTableName)
 	if err != nil {
 		// Si la tabla no existe aún, retornar vacío
 		return []int{}